@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/fiwippi/crow/internal/log"
+	"github.com/fiwippi/crow/pkg/archive"
+)
+
+// backfill archives every thread in a board's archive which hasn't been archived yet
+func backfill(args []string) {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	sf := newSettingsFlags(fs)
+	fs.Usage = usage(fs, "Archive every thread in a board's archive, threads which are already archived are skipped, "+
+		"crow exits with status 1 if any thread failed to archive",
+		"backfill po",
+		"backfill -config crow.toml -dst archive po",
+	)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	board := strings.Trim(fs.Arg(0), "/")
+	cfg := sf.load()
+
+	c := newClient(cfg, nil)
+	a := archive.New(c, options(sf.settings(cfg, board, 0, 0)))
+
+	res, err := a.Backfill(signalContext(), board)
+	log.Info().Str("board", board).Int("total", res.Total).Int("archived", res.Archived).
+		Int("skipped", res.Skipped).Int("failed", res.Failed).Msg("backfill finished")
+	if err != nil && err != context.Canceled {
+		log.Fatal().Err(err).Str("board", board).Msg("failed to backfill board")
+	}
+	if res.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fiwippi/crow/internal/config"
+	"github.com/fiwippi/crow/internal/log"
+	"github.com/fiwippi/crow/pkg/api"
+	"github.com/fiwippi/crow/pkg/archive"
+)
+
+// configFlags are the flags shared by every command, they
+// load the config file and set up the logger
+type configFlags struct {
+	fs         *flag.FlagSet
+	configPath *string
+	logLevel   *string
+	logFormat  *string
+}
+
+func newConfigFlags(fs *flag.FlagSet) *configFlags {
+	return &configFlags{
+		fs:         fs,
+		configPath: fs.String("config", "", "Path to a TOML config file, flags which are set override its values"),
+		logLevel:   fs.String("log-level", "info", "Minimum level of logs to show: trace, debug, info, warn or error"),
+		logFormat:  fs.String("log-format", "console", "Format of the logs written to stderr: console or json"),
+	}
+}
+
+// set returns whether the flag was set on the command line
+func (f *configFlags) set(name string) bool {
+	set := false
+	f.fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// override returns the flag's value if it was set or if the config's value is empty
+func (f *configFlags) override(name, flagVal, cfgVal string) string {
+	if f.set(name) || cfgVal == "" {
+		return flagVal
+	}
+	return cfgVal
+}
+
+// load reads the config file if one is given and sets up the logger
+func (f *configFlags) load() *config.Config {
+	cfg := &config.Config{}
+	if *f.configPath != "" {
+		var err error
+		cfg, err = config.Load(*f.configPath)
+		if err != nil {
+			log.Fatal().Err(err).Str("path", *f.configPath).Msg("failed to load config")
+		}
+	}
+
+	err := log.Setup(os.Stderr, f.override("log-level", *f.logLevel, cfg.Log.Level), f.override("log-format", *f.logFormat, cfg.Log.Format))
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to setup logger")
+	}
+	return cfg
+}
+
+// settingsFlags are the flags shared by the commands which archive
+// threads, flags which are set override the config file
+type settingsFlags struct {
+	*configFlags
+	dst         *string
+	overwrite   *bool
+	validateMD5 *bool
+	filesOnly   *bool
+	format      *string
+	extensions  *string
+	minSize     *string
+	maxSize     *string
+	minWidth    *int
+	minHeight   *int
+	noSpoilers  *bool
+	opOnly      *bool
+}
+
+func newSettingsFlags(fs *flag.FlagSet) *settingsFlags {
+	return &settingsFlags{
+		configFlags: newConfigFlags(fs),
+		dst:         fs.String("dst", "./", "Destination dir"),
+		overwrite:   fs.Bool("overwrite", false, "Whether to overwrite files which already exist"),
+		validateMD5: fs.Bool("validate-md5", true, "Whether to validate the MD5 hash of files"),
+		filesOnly:   fs.Bool("files-only", false, "Whether to archive only the files and not the html page of the thread, files are saved to images/ named by their 4chan file ID"),
+		format:      fs.String("format", "html", "Comma separated formats to save the thread as, html and/or json"),
+		extensions:  fs.String("extensions", "", "Comma separated extensions of files to download, e.g. webm,gif, all files are downloaded if empty"),
+		minSize:     fs.String("min-size", "0", "Minimum size of files to download, e.g. 500KB"),
+		maxSize:     fs.String("max-size", "0", "Maximum size of files to download, e.g. 4MB, no maximum if zero"),
+		minWidth:    fs.Int("min-width", 0, "Minimum width of files to download in pixels"),
+		minHeight:   fs.Int("min-height", 0, "Minimum height of files to download in pixels"),
+		noSpoilers:  fs.Bool("no-spoilers", false, "Whether to skip spoilered files"),
+		opOnly:      fs.Bool("op-only", false, "Whether to only download the file of the thread's OP"),
+	}
+}
+
+// settings returns the thread's settings, flags which are set override the
+// config file which overrides the flag defaults. The interval is only used if
+// the command has an interval flag. Files which don't match the filters set
+// by the flags or the config file are left as links to 4chan
+func (f *settingsFlags) settings(cfg *config.Config, board string, thread int, interval time.Duration) config.Settings {
+	minSize, err := config.ParseSize(*f.minSize)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to parse min-size")
+	}
+	maxSize, err := config.ParseSize(*f.maxSize)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to parse max-size")
+	}
+	extensions := make([]string, 0)
+	for _, ext := range strings.Split(*f.extensions, ",") {
+		if ext = strings.TrimSpace(ext); ext != "" {
+			extensions = append(extensions, ext)
+		}
+	}
+
+	flags := config.Settings{}
+	defaults := config.Settings{
+		Dst:         f.dst,
+		Overwrite:   f.overwrite,
+		ValidateMD5: f.validateMD5,
+		FilesOnly:   f.filesOnly,
+		Format:      f.format,
+		Interval:    &config.Duration{Duration: interval},
+		Extensions:  extensions,
+		MinFilesize: &minSize,
+		MaxFilesize: &maxSize,
+		MinWidth:    f.minWidth,
+		MinHeight:   f.minHeight,
+		NoSpoilers:  f.noSpoilers,
+		OPOnly:      f.opOnly,
+	}
+	if f.set("dst") {
+		flags.Dst = f.dst
+	}
+	if f.set("overwrite") {
+		flags.Overwrite = f.overwrite
+	}
+	if f.set("validate-md5") {
+		flags.ValidateMD5 = f.validateMD5
+	}
+	if f.set("files-only") {
+		flags.FilesOnly = f.filesOnly
+	}
+	if f.set("format") {
+		flags.Format = f.format
+	}
+	if f.set("interval") {
+		flags.Interval = defaults.Interval
+	}
+	if f.set("extensions") {
+		flags.Extensions = defaults.Extensions
+	}
+	if f.set("min-size") {
+		flags.MinFilesize = defaults.MinFilesize
+	}
+	if f.set("max-size") {
+		flags.MaxFilesize = defaults.MaxFilesize
+	}
+	if f.set("min-width") {
+		flags.MinWidth = f.minWidth
+	}
+	if f.set("min-height") {
+		flags.MinHeight = f.minHeight
+	}
+	if f.set("no-spoilers") {
+		flags.NoSpoilers = f.noSpoilers
+	}
+	if f.set("op-only") {
+		flags.OPOnly = f.opOnly
+	}
+
+	return flags.Merge(cfg.Settings(board, thread)).Merge(defaults)
+}
+
+// options returns the archive options for the settings
+func options(s config.Settings) archive.Options {
+	f, err := archive.ParseFormat(*s.Format)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to parse format")
+	}
+
+	return archive.Options{
+		Dst:         *s.Dst,
+		Overwrite:   *s.Overwrite,
+		ValidateMD5: *s.ValidateMD5,
+		FilesOnly:   *s.FilesOnly,
+		Format:      f,
+		Logger:      log.Logger(),
+		Filter: archive.Filter{
+			Extensions:  s.Extensions,
+			MinFilesize: int(*s.MinFilesize),
+			MaxFilesize: int(*s.MaxFilesize),
+			MinWidth:    *s.MinWidth,
+			MinHeight:   *s.MinHeight,
+			NoSpoilers:  *s.NoSpoilers,
+			OPOnly:      *s.OPOnly,
+		},
+	}
+}
+
+// newClient creates the client from the config, by default it's the same as api.DefaultClient()
+func newClient(cfg *config.Config, inst api.Instrumenter) *api.Client {
+	apiPerSec, mediaPerSec, ssl, ifms := 1, 8, true, true
+	if cfg.Client.APIPerSec > 0 {
+		apiPerSec = cfg.Client.APIPerSec
+	}
+	if cfg.Client.MediaPerSec > 0 {
+		mediaPerSec = cfg.Client.MediaPerSec
+	}
+	if cfg.Client.SSL != nil {
+		ssl = *cfg.Client.SSL
+	}
+	if cfg.Client.IFMS != nil {
+		ifms = *cfg.Client.IFMS
+	}
+
+	c := api.NewClient(apiPerSec, mediaPerSec, ssl, ifms)
+	c.Logger = log.Logger()
+	c.Instrumenter = inst
+	return c
+}
+
+// signalContext returns a context which is cancelled once crow receives SIGINT
+// or SIGTERM so it can shut down gracefully, a second signal kills crow
+func signalContext() context.Context {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+		log.Warn().Msg("shutting down, send the signal again to exit immediately")
+	}()
+	return ctx
+}
+
+// usage returns the usage func of a command which prints its
+// description, examples of how to use it and then its flags
+func usage(fs *flag.FlagSet, description string, examples ...string) func() {
+	return func() {
+		w := fs.Output()
+		fmt.Fprintln(w, description)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Usage:")
+		for _, e := range examples {
+			fmt.Fprintln(w, "  ./crow "+e)
+		}
+		fmt.Fprintln(w)
+		fs.PrintDefaults()
+	}
+}
@@ -1,8 +1,9 @@
 module github.com/fiwippi/crow
 
-go 1.16
+go 1.23
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/rs/zerolog v1.26.1
 	golang.org/x/net v0.0.0-20210805182204-aaa1db679c0d
 	golang.org/x/time v0.0.0-20210220033141-f8bda1e9f3ba
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fiwippi/crow/internal/log"
+	"github.com/fiwippi/crow/pkg/api"
+)
+
+// board prints every board or the settings of the boards given as arguments
+func board(args []string) {
+	fs := flag.NewFlagSet("board", flag.ExitOnError)
+	cf := newConfigFlags(fs)
+	fs.Usage = usage(fs, "Print every board or the settings of the given boards",
+		"board",
+		"board po g",
+	)
+	fs.Parse(args)
+	cfg := cf.load()
+	c := newClient(cfg, nil)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	if fs.NArg() == 0 {
+		boards, _, err := c.GetBoards()
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to get boards")
+		}
+		fmt.Fprintln(w, "BOARD\tTITLE\tPAGES\tWORKSAFE\tARCHIVED")
+		for _, b := range boards.Boards {
+			fmt.Fprintf(w, "/%s/\t%s\t%d\t%t\t%t\n", b.Board, b.Title, b.Pages, b.WorkSafe, b.IsArchived)
+		}
+		return
+	}
+
+	for i, name := range fs.Args() {
+		b, err := c.Board(strings.Trim(name, "/"))
+		if err != nil {
+			log.Fatal().Err(err).Str("board", name).Msg("failed to get board")
+		}
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Board:\t/%s/ - %s\n", b.Board, b.Title)
+		fmt.Fprintf(w, "Worksafe:\t%t\n", b.WorkSafe)
+		fmt.Fprintf(w, "Pages:\t%d x %d threads\n", b.Pages, b.PerPage)
+		fmt.Fprintf(w, "Bump limit:\t%d\n", b.BumpLimit)
+		fmt.Fprintf(w, "Image limit:\t%d\n", b.ImageLimit)
+		fmt.Fprintf(w, "Max filesize:\t%d KB (webm %d KB)\n", b.MaxFilesize, b.MaxWebmFilesize)
+		fmt.Fprintf(w, "Archived:\t%t\n", b.IsArchived)
+		fmt.Fprintf(w, "Flags:\t%t\n", b.HasFlags())
+		fmt.Fprintf(w, "Spoilers:\t%t\n", b.Spoilers)
+		fmt.Fprintf(w, "Text only:\t%t\n", b.TextOnly)
+	}
+}
+
+// catalog prints the threads in a board's catalog
+func catalog(args []string) {
+	fs := flag.NewFlagSet("catalog", flag.ExitOnError)
+	cf := newConfigFlags(fs)
+	lf := newListFlags(fs, "page")
+	fs.Usage = usage(fs, "Print the threads in a board's catalog, sorted and filtered by their subject or comment",
+		"catalog po",
+		"catalog -sort replies -reverse -limit 10 po",
+		"catalog -subject '(?i)general' -output ndjson g",
+	)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	cfg := cf.load()
+	rf := lf.parse()
+	c := newClient(cfg, nil)
+
+	cat, _, err := c.GetCatalog(strings.Trim(fs.Arg(0), "/"))
+	if err != nil {
+		log.Fatal().Err(err).Str("board", fs.Arg(0)).Msg("failed to get catalog")
+	}
+
+	rows := make([]row, 0)
+	for _, p := range cat.Pages {
+		for _, t := range p.Threads {
+			rows = append(rows, newRow(t, p.Page, t.Replies, t.Images))
+		}
+	}
+
+	err = rf.print(os.Stdout, rows, []column{colNo, colSubject, colReplies, colImages, colAge, colPage})
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to print catalog")
+	}
+}
+
+// thread prints the posts in a thread
+func thread(args []string) {
+	fs := flag.NewFlagSet("thread", flag.ExitOnError)
+	cf := newConfigFlags(fs)
+	lf := newListFlags(fs, "no")
+	fs.Usage = usage(fs, "Print the posts in a thread, sorted and filtered by their subject or comment, "+
+		"replies are the posts in the thread which quote the post",
+		"thread po 570368",
+		"thread -sort replies -reverse -limit 5 https://boards.4channel.org/po/thread/570368",
+		"thread -comment '(?i)papercraft' -output json '>>>/po/570368'",
+	)
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	board, no, err := parseThread(fs.Args())
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to parse 4chan url")
+	}
+	cfg := cf.load()
+	rf := lf.parse()
+	c := newClient(cfg, nil)
+
+	t, _, err := c.GetThread(board, no)
+	if err != nil {
+		log.Fatal().Err(err).Int("no", no).Str("board", board).Msg("failed to get thread")
+	}
+
+	g := t.ReplyGraph()
+	rows := make([]row, len(t.Posts))
+	for i, p := range t.Posts {
+		images := 0
+		if p.HasFile {
+			images = 1
+		}
+		rows[i] = newRow(p, 0, len(g.Backlinks(p.No)), images)
+	}
+
+	err = rf.print(os.Stdout, rows, []column{colNo, colSubject, colReplies, colImages, colAge})
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to print thread")
+	}
+}
+
+// row is a thread in a catalog or a post in a thread
+type row struct {
+	post    *api.Post
+	page    int    // The page the thread is on, zero for posts
+	replies int    // The thread's replies or the number of posts quoting the post
+	images  int    // The thread's images or whether the post has a file
+	text    string // The comment as plain text
+}
+
+func newRow(p *api.Post, page, replies, images int) row {
+	return row{post: p, page: page, replies: replies, images: images, text: p.PlainText()}
+}
+
+// column is a column of the table which rows can be sorted by
+type column struct {
+	name  string
+	value func(r row) string
+	less  func(a, b row) bool
+}
+
+var (
+	colNo = column{"no",
+		func(r row) string { return fmt.Sprint(r.post.No) },
+		func(a, b row) bool { return a.post.No < b.post.No },
+	}
+	colSubject = column{"subject",
+		func(r row) string { return r.summary(60) },
+		func(a, b row) bool { return strings.ToLower(a.summary(0)) < strings.ToLower(b.summary(0)) },
+	}
+	colReplies = column{"replies",
+		func(r row) string { return fmt.Sprint(r.replies) },
+		func(a, b row) bool { return a.replies < b.replies },
+	}
+	colImages = column{"images",
+		func(r row) string { return fmt.Sprint(r.images) },
+		func(a, b row) bool { return a.images < b.images },
+	}
+	colAge = column{"age",
+		func(r row) string { return formatAge(time.Since(r.post.Time.Time)) },
+		func(a, b row) bool { return a.post.Time.After(b.post.Time.Time) },
+	}
+	colPage = column{"page",
+		func(r row) string { return fmt.Sprint(r.page) },
+		func(a, b row) bool { return a.page < b.page },
+	}
+)
+
+// summary returns the subject or the start of the comment if there isn't
+// one on a single line, it's truncated to n characters if n is positive
+func (r row) summary(n int) string {
+	s := r.post.Subject
+	if s == "" {
+		s = r.text
+	}
+	s = strings.Join(strings.Fields(s), " ")
+	if n > 0 && utf8.RuneCountInString(s) > n {
+		s = string([]rune(s)[:n-3]) + "..."
+	}
+	return s
+}
+
+// listFlags are the flags of the commands which print threads or posts
+type listFlags struct {
+	output  *string
+	sort    *string
+	reverse *bool
+	limit   *int
+	subject *string
+	comment *string
+}
+
+func newListFlags(fs *flag.FlagSet, sortBy string) *listFlags {
+	return &listFlags{
+		output:  fs.String("output", "table", "Format to print in: table, json or ndjson"),
+		sort:    fs.String("sort", sortBy, "Column to sort by: no, subject, replies, images, age or page"),
+		reverse: fs.Bool("reverse", false, "Whether to reverse the sort order"),
+		limit:   fs.Int("limit", 0, "Maximum number of rows to print, all are printed if zero"),
+		subject: fs.String("subject", "", "Only print rows whose subject matches the regex, use (?i) to ignore case"),
+		comment: fs.String("comment", "", "Only print rows whose comment as plain text matches the regex, use (?i) to ignore case"),
+	}
+}
+
+// rowFilter decides which rows are printed and how
+type rowFilter struct {
+	output  string
+	sort    column
+	reverse bool
+	limit   int
+	subject *regexp.Regexp
+	comment *regexp.Regexp
+}
+
+// parse validates the flags, invalid flags are fatal
+func (f *listFlags) parse() rowFilter {
+	rf := rowFilter{output: *f.output, reverse: *f.reverse, limit: *f.limit}
+	switch rf.output {
+	case "table", "json", "ndjson":
+	default:
+		log.Fatal().Str("output", rf.output).Msg("output should be table, json or ndjson")
+	}
+
+	found := false
+	for _, c := range []column{colNo, colSubject, colReplies, colImages, colAge, colPage} {
+		if c.name == *f.sort {
+			rf.sort, found = c, true
+		}
+	}
+	if !found {
+		log.Fatal().Str("sort", *f.sort).Msg("unknown column to sort by")
+	}
+
+	var err error
+	if *f.subject != "" {
+		rf.subject, err = regexp.Compile(*f.subject)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to parse subject regex")
+		}
+	}
+	if *f.comment != "" {
+		rf.comment, err = regexp.Compile(*f.comment)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to parse comment regex")
+		}
+	}
+	return rf
+}
+
+// apply returns the rows which match the filters in sorted order
+func (rf rowFilter) apply(rows []row) []row {
+	matched := make([]row, 0, len(rows))
+	for _, r := range rows {
+		if rf.subject != nil && !rf.subject.MatchString(r.post.Subject) {
+			continue
+		}
+		if rf.comment != nil && !rf.comment.MatchString(r.text) {
+			continue
+		}
+		matched = append(matched, r)
+	}
+
+	// The sort is stable so catalog threads stay in bump order within their page
+	sort.SliceStable(matched, func(i, j int) bool {
+		if rf.reverse {
+			return rf.sort.less(matched[j], matched[i])
+		}
+		return rf.sort.less(matched[i], matched[j])
+	})
+
+	if rf.limit > 0 && len(matched) > rf.limit {
+		matched = matched[:rf.limit]
+	}
+	return matched
+}
+
+// print writes the matching rows as a table with the columns or
+// writes their posts as a JSON array or as one JSON object per line
+func (rf rowFilter) print(w io.Writer, rows []row, cols []column) error {
+	rows = rf.apply(rows)
+
+	switch rf.output {
+	case "json":
+		posts := make([]*api.Post, len(rows))
+		for i, r := range rows {
+			posts[i] = r.post
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(posts)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, r := range rows {
+			if err := enc.Encode(r.post); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = strings.ToUpper(c.name)
+	}
+	fmt.Fprintln(tw, strings.Join(names, "\t"))
+	for _, r := range rows {
+		values := make([]string, len(cols))
+		for i, c := range cols {
+			values[i] = c.value(r)
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+	return tw.Flush()
+}
+
+// formatAge formats the duration in its two largest units, e.g. "3d4h" or "12m"
+func formatAge(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+
+	units := []struct {
+		suffix string
+		d      time.Duration
+	}{{"d", 24 * time.Hour}, {"h", time.Hour}, {"m", time.Minute}}
+
+	var b strings.Builder
+	parts := 0
+	for _, u := range units {
+		if n := d / u.d; n > 0 || parts > 0 {
+			fmt.Fprintf(&b, "%d%s", n, u.suffix)
+			d -= n * u.d
+			parts++
+		}
+		if parts == 2 {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,210 @@
+// Package config loads the TOML config file used by the crow CLI
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Duration is a time.Duration which is written as a string in the
+// config file, e.g. "5m" or "1h30m"
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalTOML(v interface{}) error {
+	s, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("durations should be strings, e.g. \"5m\", got: %v", v)
+	}
+
+	var err error
+	d.Duration, err = time.ParseDuration(s)
+	return err
+}
+
+// Size is a number of bytes which can be written in the config file as
+// a number or as a string with a unit, e.g. "500KB" or "2MB"
+type Size int64
+
+func (sz *Size) UnmarshalTOML(v interface{}) error {
+	switch v := v.(type) {
+	case int64:
+		*sz = Size(v)
+		return nil
+	case string:
+		var err error
+		*sz, err = ParseSize(v)
+		return err
+	default:
+		return fmt.Errorf("sizes should be numbers or strings, e.g. \"2MB\", got: %v", v)
+	}
+}
+
+// ParseSize parses a number of bytes with an optional unit, i.e.
+// B, KB, MB or GB where a KB is 1024 bytes, e.g. "500KB" or "2MB"
+func ParseSize(s string) (Size, error) {
+	num := strings.ToUpper(strings.TrimSpace(s))
+	unit := Size(1)
+	for _, u := range []struct {
+		suffix string
+		size   Size
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"B", 1}} {
+		if strings.HasSuffix(num, u.suffix) {
+			num, unit = strings.TrimSpace(strings.TrimSuffix(num, u.suffix)), u.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size: %q", s)
+	}
+	return Size(n * float64(unit)), nil
+}
+
+// Client configures the api client
+type Client struct {
+	APIPerSec   int   `toml:"api_per_sec"`       // Requests per second to the api
+	MediaPerSec int   `toml:"media_per_sec"`     // Requests per second to the media endpoints
+	SSL         *bool `toml:"ssl"`               // Whether to use HTTPS
+	IFMS        *bool `toml:"if_modified_since"` // Whether to send the If-Modified-Since header
+}
+
+// Settings configure how threads are archived, unset
+// fields are inherited from the enclosing settings
+type Settings struct {
+	Dst         *string   `toml:"dst"`          // Destination dir
+	Overwrite   *bool     `toml:"overwrite"`    // Whether to overwrite files which already exist
+	ValidateMD5 *bool     `toml:"validate_md5"` // Whether to validate the MD5 hash of files
+	FilesOnly   *bool     `toml:"files_only"`   // Whether to archive only the files and not the thread itself
+	Format      *string   `toml:"format"`       // Comma separated formats to save the thread as
+	Interval    *Duration `toml:"interval"`     // How often to check if the thread updated
+
+	// Filters decide which files are downloaded
+	Extensions  []string `toml:"extensions"`   // Extensions of files to download, e.g. [".webm", ".gif"]
+	MinFilesize *Size    `toml:"min_filesize"` // Minimum size of files to download
+	MaxFilesize *Size    `toml:"max_filesize"` // Maximum size of files to download
+	MinWidth    *int     `toml:"min_width"`    // Minimum width of files to download in pixels
+	MinHeight   *int     `toml:"min_height"`   // Minimum height of files to download in pixels
+	NoSpoilers  *bool    `toml:"no_spoilers"`  // Whether to skip spoilered files
+	OPOnly      *bool    `toml:"op_only"`      // Whether to only download the file of the thread's OP
+}
+
+// Merge returns s with its unset fields taken from parent
+func (s Settings) Merge(parent Settings) Settings {
+	if s.Dst == nil {
+		s.Dst = parent.Dst
+	}
+	if s.Overwrite == nil {
+		s.Overwrite = parent.Overwrite
+	}
+	if s.ValidateMD5 == nil {
+		s.ValidateMD5 = parent.ValidateMD5
+	}
+	if s.FilesOnly == nil {
+		s.FilesOnly = parent.FilesOnly
+	}
+	if s.Format == nil {
+		s.Format = parent.Format
+	}
+	if s.Interval == nil {
+		s.Interval = parent.Interval
+	}
+	if s.Extensions == nil {
+		s.Extensions = parent.Extensions
+	}
+	if s.MinFilesize == nil {
+		s.MinFilesize = parent.MinFilesize
+	}
+	if s.MaxFilesize == nil {
+		s.MaxFilesize = parent.MaxFilesize
+	}
+	if s.MinWidth == nil {
+		s.MinWidth = parent.MinWidth
+	}
+	if s.MinHeight == nil {
+		s.MinHeight = parent.MinHeight
+	}
+	if s.NoSpoilers == nil {
+		s.NoSpoilers = parent.NoSpoilers
+	}
+	if s.OPOnly == nil {
+		s.OPOnly = parent.OPOnly
+	}
+	return s
+}
+
+// Watch is a thread which should be watched
+type Watch struct {
+	Settings
+
+	Board  string `toml:"board"`
+	Thread int    `toml:"thread"`
+}
+
+// Config is the contents of the config file
+type Config struct {
+	Log struct {
+		Level  string `toml:"level"`  // Minimum level of logs to show
+		Format string `toml:"format"` // Format of the logs, console or json
+	} `toml:"log"`
+	MetricsAddr string              `toml:"metrics_addr"` // Address to serve Prometheus metrics on
+	Client      Client              `toml:"client"`       // Settings for the api client
+	Defaults    Settings            `toml:"defaults"`     // Settings used for all threads
+	Boards      map[string]Settings `toml:"boards"`       // Settings used for threads on specific boards
+	Watch       []Watch             `toml:"watch"`        // Threads to watch
+}
+
+// Load reads the config file at the path
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Parse(f)
+}
+
+// Parse reads a config file from r
+func Parse(r io.Reader) (*Config, error) {
+	var c Config
+	md, err := toml.NewDecoder(r).Decode(&c)
+	if err != nil {
+		return nil, err
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("unknown key: %s", undecoded[0])
+	}
+
+	for i, w := range c.Watch {
+		if w.Board == "" || w.Thread == 0 {
+			return nil, fmt.Errorf("watch %d: board and thread must be set", i+1)
+		}
+		c.Watch[i].Board = strings.Trim(w.Board, "/")
+	}
+
+	return &c, nil
+}
+
+// Settings returns the settings for the thread, these are the settings of
+// the watched thread if it's in the watch list, then the board's settings
+// and then the default settings
+func (c *Config) Settings(board string, thread int) Settings {
+	board = strings.Trim(board, "/")
+
+	s := c.Boards[board].Merge(c.Defaults)
+	for _, w := range c.Watch {
+		if w.Board == board && w.Thread == thread {
+			return w.Settings.Merge(s)
+		}
+	}
+	return s
+}
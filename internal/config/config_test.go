@@ -0,0 +1,185 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfig = `
+# Logging
+metrics_addr = ":9090"
+
+[log]
+level = "debug"
+format = 'json'
+
+[client]
+api_per_sec = 1
+media_per_sec = 4
+ssl = true
+
+[defaults]
+dst = "/srv/crow" # Where to save threads
+validate_md5 = true
+format = "html,json"
+interval = "5m"
+
+[boards.po]
+interval = "10m"
+files_only = true
+
+[boards.wsg]
+extensions = [".webm", "gif"]
+min_filesize = "500KB"
+max_filesize = 4194304
+min_width = 640
+no_spoilers = true
+
+[[watch]]
+board = "/po/"
+thread = 570_368
+interval = "1m"
+
+[[watch]]
+board = "g"
+thread = 1
+dst = "C:\\archive #2"
+`
+
+func TestParse(t *testing.T) {
+	c, err := Parse(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatalf("failed to parse config: %s\n", err)
+	}
+
+	if c.MetricsAddr != ":9090" || c.Log.Level != "debug" || c.Log.Format != "json" {
+		t.Errorf("top level settings parsed incorrectly: %+v\n", c)
+	}
+	if c.Client.APIPerSec != 1 || c.Client.MediaPerSec != 4 || c.Client.SSL == nil || !*c.Client.SSL || c.Client.IFMS != nil {
+		t.Errorf("client settings parsed incorrectly: %+v\n", c.Client)
+	}
+	if len(c.Watch) != 2 || c.Watch[0].Board != "po" || c.Watch[0].Thread != 570368 {
+		t.Fatalf("watch list parsed incorrectly: %+v\n", c.Watch)
+	}
+
+	// Watched thread settings override board settings which override defaults
+	s := c.Settings("po", 570368)
+	if s.Interval.Duration != time.Minute {
+		t.Errorf("watch interval not used, got: %s\n", s.Interval)
+	}
+	if !*s.FilesOnly {
+		t.Errorf("board files_only not used\n")
+	}
+	if *s.Dst != "/srv/crow" || *s.Format != "html,json" || !*s.ValidateMD5 || s.Overwrite != nil {
+		t.Errorf("default settings not used: %+v\n", s)
+	}
+
+	// Unwatched threads use the board's settings
+	s = c.Settings("po", 1)
+	if s.Interval.Duration != 10*time.Minute {
+		t.Errorf("board interval not used, got: %s\n", s.Interval)
+	}
+
+	// Strings can contain escapes and comment characters
+	s = c.Settings("g", 1)
+	if *s.Dst != `C:\archive #2` {
+		t.Errorf("string parsed incorrectly, got: %s\n", *s.Dst)
+	}
+	if s.FilesOnly != nil {
+		t.Errorf("board settings applied to the wrong board\n")
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	c, err := Parse(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatalf("failed to parse config: %s\n", err)
+	}
+
+	s := c.Settings("wsg", 1)
+	if len(s.Extensions) != 2 || s.Extensions[0] != ".webm" || s.Extensions[1] != "gif" {
+		t.Errorf("extensions parsed incorrectly: %v\n", s.Extensions)
+	}
+	if *s.MinFilesize != 500*1024 || *s.MaxFilesize != 4*1024*1024 || *s.MinWidth != 640 || !*s.NoSpoilers {
+		t.Errorf("filters parsed incorrectly: %+v\n", s)
+	}
+	if s.MinHeight != nil || s.OPOnly != nil {
+		t.Errorf("unset filters should be nil: %+v\n", s)
+	}
+	if s = c.Settings("po", 1); s.Extensions != nil || s.MinFilesize != nil {
+		t.Errorf("filters applied to the wrong board: %+v\n", s)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Size
+		err  bool
+	}{
+		{"1024", 1024, false},
+		{"500B", 500, false},
+		{"500KB", 500 * 1024, false},
+		{"1.5 mb", 1536 * 1024, false},
+		{"2GB", 2 << 30, false},
+		{"", 0, true},
+		{"lots", 0, true},
+		{"-1MB", 0, true},
+	}
+
+	for _, tc := range tests {
+		got, err := ParseSize(tc.in)
+		if (err != nil) != tc.err || got != tc.want {
+			t.Errorf("size %q parsed as %d, err: %v but expected %d\n", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	invalid := []string{
+		"dst = ",
+		"[defaults\ndst = \"a\"",
+		"[defaults]\ndst = \"a\"\ndst = \"b\"",
+		"[defaults]\ndst = \"unterminated",
+		"[defaults]\nunknown = 1",
+		"[defaults]\ninterval = 5",
+		"[defaults]\ninterval = \"5 minutes\"",
+		"[defaults]\nmax_filesize = \"lots\"",
+		"[defaults]\nextensions = \"webm\"",
+		"[[watch]]\nboard = \"po\"",
+		"[client]\napi_per_sec = [1, 2",
+	}
+
+	for _, s := range invalid {
+		_, err := Parse(strings.NewReader(s))
+		if err == nil {
+			t.Errorf("invalid config parsed without error: %q\n", s)
+		}
+	}
+}
+
+func TestParseSyntax(t *testing.T) {
+	c, err := Parse(strings.NewReader(`
+[defaults]
+extensions = [
+  ".webm",
+  ".gif",
+]
+dst = '''/srv/crow'''
+
+[boards]
+po = { dst = "/srv/po", min_filesize = 1024 }
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %s\n", err)
+	}
+
+	s := c.Settings("g", 1)
+	if len(s.Extensions) != 2 || s.Extensions[1] != ".gif" || *s.Dst != "/srv/crow" {
+		t.Errorf("multi-line array or string parsed incorrectly: %+v\n", s)
+	}
+	if s = c.Settings("po", 1); *s.Dst != "/srv/po" || *s.MinFilesize != 1024 {
+		t.Errorf("inline table parsed incorrectly: %+v\n", s)
+	}
+}
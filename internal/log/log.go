@@ -1,37 +1,64 @@
 package log
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 )
 
-func init() {
-	zerolog.SetGlobalLevel(zerolog.DebugLevel)
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "15:04:05"})
+// logger is used by crow, it writes to stderr until Setup is called
+var logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: "15:04:05"}).
+	With().Timestamp().Logger().
+	Level(zerolog.InfoLevel)
+
+// Setup configures the logger to write to w at the given level. The format
+// is either "console" for human-readable output or "json"
+func Setup(w io.Writer, level, format string) error {
+	lvl, err := zerolog.ParseLevel(strings.ToLower(level))
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(format) {
+	case "console":
+		w = zerolog.ConsoleWriter{Out: w, TimeFormat: "15:04:05"}
+	case "json":
+	default:
+		return fmt.Errorf("invalid log format: %q", format)
+	}
+
+	logger = zerolog.New(w).With().Timestamp().Logger().Level(lvl)
+	return nil
+}
+
+// Logger returns the logger used by crow
+func Logger() *zerolog.Logger {
+	return &logger
 }
 
 func Error() *zerolog.Event {
-	return log.Error()
+	return logger.Error()
 }
 
 func Warn() *zerolog.Event {
-	return log.Warn()
+	return logger.Warn()
 }
 
 func Fatal() *zerolog.Event {
-	return log.Fatal()
+	return logger.Fatal()
 }
 
 func Info() *zerolog.Event {
-	return log.Info()
+	return logger.Info()
 }
 
 func Debug() *zerolog.Event {
-	return log.Debug()
+	return logger.Debug()
 }
 
 func Trace() *zerolog.Event {
-	return log.Trace()
+	return logger.Trace()
 }
@@ -0,0 +1,201 @@
+// Package metrics records metrics about crow and exposes
+// them in the Prometheus text format
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fiwippi/crow/pkg/api"
+	"github.com/fiwippi/crow/pkg/archive"
+)
+
+// summary keeps the sum and count of observations
+type summary struct {
+	sum   float64
+	count float64
+}
+
+// Metrics records requests made by the api client and events from
+// the archiver. It implements api.Instrumenter, archive.Observer and
+// http.Handler so it can be served as a /metrics endpoint
+type Metrics struct {
+	mu sync.Mutex
+
+	requests        map[string]float64  // Keyed by `domain="",status=""`
+	waits           map[string]*summary // Keyed by domain
+	durations       map[string]*summary // Keyed by domain
+	downloaded      map[string]float64  // Keyed by domain
+	filesSaved      map[string]float64  // Keyed by kind
+	filesFailed     map[string]float64  // Keyed by kind
+	filesSkipped    map[string]float64  // Keyed by kind
+	bytesSaved      float64
+	md5Failures     float64
+	archiveFailures float64
+	watching        map[string]struct{} // Threads currently being watched keyed by board/no
+	stopped         map[string]float64  // Threads which stopped being watched keyed by state
+}
+
+// New creates an empty Metrics
+func New() *Metrics {
+	return &Metrics{
+		requests:     make(map[string]float64),
+		waits:        make(map[string]*summary),
+		durations:    make(map[string]*summary),
+		downloaded:   make(map[string]float64),
+		filesSaved:   make(map[string]float64),
+		filesFailed:  make(map[string]float64),
+		filesSkipped: make(map[string]float64),
+		watching:     make(map[string]struct{}),
+		stopped:      make(map[string]float64),
+	}
+}
+
+// ObserveRequest implements api.Instrumenter
+func (m *Metrics) ObserveRequest(r api.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := "error"
+	if r.Status != 0 {
+		status = strconv.Itoa(r.Status)
+	}
+	m.requests[fmt.Sprintf("domain=%q,status=%q", r.Domain, status)]++
+	observe(m.waits, r.Domain, r.Waited.Seconds())
+	observe(m.durations, r.Domain, r.Duration.Seconds())
+}
+
+// ObserveBytes implements api.Instrumenter
+func (m *Metrics) ObserveBytes(domain string, n int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.downloaded[domain] += float64(n)
+}
+
+// Observe implements archive.Observer
+func (m *Metrics) Observe(e archive.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch e.Type {
+	case archive.FileSaved:
+		m.filesSaved[e.Kind]++
+		m.bytesSaved += float64(e.Bytes)
+	case archive.FileFailed:
+		m.filesFailed[e.Kind]++
+	case archive.FileSkipped:
+		m.filesSkipped[e.Kind]++
+	case archive.MD5Mismatch:
+		m.md5Failures++
+	case archive.ArchiveFailed:
+		m.archiveFailures++
+	case archive.StateChanged:
+		// Only watched threads are kept so threads which stopped being
+		// watched don't accumulate while crow is serving metrics
+		key := fmt.Sprintf("%s/%d", e.Board, e.No)
+		if e.State == archive.StateWatching {
+			m.watching[key] = struct{}{}
+		} else {
+			delete(m.watching, key)
+			m.stopped[e.State.String()]++
+		}
+	}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+
+	write(&b, "crow_api_requests_total", "Requests made by the api client by domain and status code.", "counter",
+		labelled(m.requests, ""))
+	writeSummary(&b, "crow_api_limiter_wait_seconds", "Time requests spent waiting on the rate limiter.", m.waits)
+	writeSummary(&b, "crow_api_request_duration_seconds", "Time taken for requests to receive a response.", m.durations)
+	write(&b, "crow_api_downloaded_bytes_total", "Bytes downloaded by the api client by domain.", "counter",
+		labelled(m.downloaded, "domain"))
+	write(&b, "crow_archive_files_saved_total", "Files saved to disk by kind.", "counter",
+		labelled(m.filesSaved, "kind"))
+	write(&b, "crow_archive_files_failed_total", "Files which failed to download or save by kind.", "counter",
+		labelled(m.filesFailed, "kind"))
+	write(&b, "crow_archive_files_skipped_total", "Files which already existed or did not match the filter by kind.", "counter",
+		labelled(m.filesSkipped, "kind"))
+	write(&b, "crow_archive_saved_bytes_total", "Bytes saved to disk.", "counter",
+		[]string{value("", m.bytesSaved)})
+	write(&b, "crow_archive_md5_mismatches_total", "Downloaded files whose MD5 hash did not match the api.", "counter",
+		[]string{value("", m.md5Failures)})
+	write(&b, "crow_archive_failures_total", "Times a thread could not be fetched or archived.", "counter",
+		[]string{value("", m.archiveFailures)})
+
+	stopped := make(map[string]float64)
+	for _, s := range []archive.State{archive.StateNotFound, archive.StateArchived, archive.StateStale, archive.StateStopped} {
+		stopped[s.String()] = m.stopped[s.String()]
+	}
+	write(&b, "crow_watch_threads", "Threads currently being watched.", "gauge",
+		[]string{value("", float64(len(m.watching)))})
+	write(&b, "crow_watch_threads_stopped_total", "Threads which stopped being watched by the state they stopped in.", "counter",
+		labelled(stopped, "state"))
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func observe(s map[string]*summary, key string, v float64) {
+	if s[key] == nil {
+		s[key] = &summary{}
+	}
+	s[key].sum += v
+	s[key].count++
+}
+
+// labelled formats each value with its labels, if name is empty
+// then the keys are assumed to already be formatted labels
+func labelled(values map[string]float64, name string) []string {
+	samples := make([]string, 0, len(values))
+	for k, v := range values {
+		if name != "" {
+			k = fmt.Sprintf("%s=%q", name, k)
+		}
+		samples = append(samples, value("{"+k+"}", v))
+	}
+	sort.Strings(samples)
+	return samples
+}
+
+func value(labels string, v float64) string {
+	return labels + " " + strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func write(b *strings.Builder, name, help, typ string, samples []string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	for _, s := range samples {
+		fmt.Fprintf(b, "%s%s\n", name, s)
+	}
+}
+
+func writeSummary(b *strings.Builder, name, help string, s map[string]*summary) {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s summary\n", name, help, name)
+	for _, k := range keys {
+		fmt.Fprintf(b, "%s_sum{domain=%q} %s\n", name, k, strconv.FormatFloat(s[k].sum, 'g', -1, 64))
+		fmt.Fprintf(b, "%s_count{domain=%q} %s\n", name, k, strconv.FormatFloat(s[k].count, 'g', -1, 64))
+	}
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fiwippi/crow/pkg/api"
+	"github.com/fiwippi/crow/pkg/archive"
+)
+
+func TestMetricsOutput(t *testing.T) {
+	m := New()
+	m.ObserveRequest(api.Request{Domain: api.ApiDomain, Status: 200, Waited: time.Second, Duration: time.Second})
+	m.ObserveRequest(api.Request{Domain: api.ApiDomain, Status: 304, Waited: time.Second, Duration: time.Second})
+	m.ObserveRequest(api.Request{Domain: api.MediaDomainA, Err: api.ErrNotFound})
+	m.ObserveBytes(api.MediaDomainA, 1024)
+	m.Observe(archive.Event{Type: archive.FileSaved, Kind: "images", Bytes: 512})
+	m.Observe(archive.Event{Type: archive.FileFailed, Kind: "thumbs"})
+	m.Observe(archive.Event{Type: archive.FileSkipped, Kind: "images"})
+	m.Observe(archive.Event{Type: archive.MD5Mismatch})
+	m.Observe(archive.Event{Type: archive.ArchiveFailed, Board: "po", No: 3})
+	m.Observe(archive.Event{Type: archive.StateChanged, Board: "po", No: 1, State: archive.StateWatching})
+	m.Observe(archive.Event{Type: archive.StateChanged, Board: "po", No: 2, State: archive.StateWatching})
+	m.Observe(archive.Event{Type: archive.StateChanged, Board: "po", No: 2, State: archive.StateNotFound})
+
+	var b strings.Builder
+	_, err := m.WriteTo(&b)
+	if err != nil {
+		t.Fatalf("failed to write metrics: %s\n", err)
+	}
+	out := b.String()
+
+	expected := []string{
+		`crow_api_requests_total{domain="a.4cdn.org",status="200"} 1`,
+		`crow_api_requests_total{domain="a.4cdn.org",status="304"} 1`,
+		`crow_api_requests_total{domain="i.4cdn.org",status="error"} 1`,
+		`crow_api_limiter_wait_seconds_sum{domain="a.4cdn.org"} 2`,
+		`crow_api_limiter_wait_seconds_count{domain="a.4cdn.org"} 2`,
+		`crow_api_downloaded_bytes_total{domain="i.4cdn.org"} 1024`,
+		`crow_archive_files_saved_total{kind="images"} 1`,
+		`crow_archive_files_failed_total{kind="thumbs"} 1`,
+		`crow_archive_files_skipped_total{kind="images"} 1`,
+		`crow_archive_saved_bytes_total 512`,
+		`crow_archive_md5_mismatches_total 1`,
+		`crow_archive_failures_total 1`,
+		`crow_watch_threads 1`,
+		`crow_watch_threads_stopped_total{state="not_found"} 1`,
+		`crow_watch_threads_stopped_total{state="archived"} 0`,
+		"# TYPE crow_watch_threads gauge",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e+"\n") {
+			t.Errorf("metrics output missing line: %s\n", e)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/rs/zerolog"
 	"golang.org/x/time/rate"
 )
 
@@ -21,6 +22,9 @@ var gmt = func() *time.Location {
 
 var ErrNotFound = fmt.Errorf("404 not found")
 
+// nop is used when the client has no logger
+var nop = zerolog.Nop()
+
 const (
 	ApiDomain    = "a.4cdn.org"       // This domain serves all 4chan API endpoints in the form of static json files.
 	MediaDomainA = "i.4cdn.org"       // This is the primary content domain used for serving user submitted media attached to posts.
@@ -46,6 +50,13 @@ type Client struct {
 	// code is returned if no changes have occurred since then (meaning
 	// no new content exists)
 	IFMS bool
+	// Logger logs the requests made by the client, if nil then nothing is logged
+	Logger *zerolog.Logger
+	// Instrumenter records metrics about the requests made by the client, may be nil
+	Instrumenter Instrumenter
+
+	boards *boardCache     // Cached boards.json used to validate boards
+	ctx    context.Context // Cancels the client's requests, may be nil
 }
 
 // DefaultClient returns client with at most 1 request to the
@@ -63,6 +74,7 @@ func NewClient(apiPerSec, mediaPerSec int, ssl, ifms bool) *Client {
 		mediaLimiter: rate.NewLimiter(rate.Every(time.Second/time.Duration(mediaPerSec)), 1),
 		SSL:          ssl,
 		IFMS:         ifms,
+		boards:       &boardCache{},
 	}
 }
 
@@ -70,14 +82,14 @@ func NewClient(apiPerSec, mediaPerSec int, ssl, ifms bool) *Client {
 // specific subdomain, errors are returned on status codes 400-500
 func (c *Client) do(method, domain, board, endpoint string, lastAccessed time.Time) (*http.Response, time.Time, error) {
 	// Create the *http.Request
-	req, err := http.NewRequest(method, c.url(domain, board, endpoint), nil)
+	req, err := http.NewRequestWithContext(c.context(), method, c.url(domain, board, endpoint), nil)
 	if err != nil {
 		return nil, time.Time{}, err
 	}
 
 	// Setting the If-Modified-Since Header
 	if c.IFMS && lastAccessed != (time.Time{}) {
-		req.Header.Set("If-Modified-Since", lastAccessed.Format("Mon, 02 Jan 2006 15:04:05 GMT"))
+		req.Header.Set("If-Modified-Since", lastAccessed.In(gmt).Format("Mon, 02 Jan 2006 15:04:05 GMT"))
 	}
 
 	// Choose the correct rate limiter and http client
@@ -95,29 +107,73 @@ func (c *Client) do(method, domain, board, endpoint string, lastAccessed time.Ti
 	}
 
 	// Rate limit if needed and send the request
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(c.context(), 30*time.Second)
 	defer cancel()
 
+	log := c.logger()
+	start := time.Now()
 	err = rl.Wait(ctx)
 	if err != nil {
 		return nil, time.Time{}, err
 	}
+	waited := time.Since(start)
+	log.Trace().Str("url", req.URL.String()).Dur("waited", waited).Msg("rate limiter passed")
+
 	t := time.Now().In(gmt)
 	resp, err := client.Do(req)
 	if err != nil {
+		log.Debug().Err(err).Str("method", method).Str("url", req.URL.String()).Msg("request failed")
+		if c.Instrumenter != nil {
+			c.Instrumenter.ObserveRequest(Request{Method: method, Domain: domain, Waited: waited, Duration: time.Since(t), Err: err})
+		}
 		return nil, time.Time{}, err
 	}
+	log.Debug().Str("method", method).Str("url", req.URL.String()).Int("status", resp.StatusCode).
+		Dur("took", time.Since(t)).Msg("request done")
+	if c.Instrumenter != nil {
+		c.Instrumenter.ObserveRequest(Request{Method: method, Domain: domain, Status: resp.StatusCode, Waited: waited, Duration: time.Since(t)})
+		resp.Body = &countingBody{ReadCloser: resp.Body, domain: domain, inst: c.Instrumenter}
+	}
 
 	// Returns an error on status codes 400-500
 	if resp.StatusCode == 404 {
+		resp.Body.Close()
 		return nil, t, ErrNotFound
 	} else if resp.StatusCode >= 400 && resp.StatusCode <= 500 {
+		resp.Body.Close()
 		return nil, t, fmt.Errorf("response from api has invalid status: %d", resp.StatusCode)
 	}
 
 	return resp, t, nil
 }
 
+// WithContext returns a shallow copy of the client whose requests are
+// cancelled once the context is done. The copy shares its rate limits
+// and cached boards with the original client
+func (c *Client) WithContext(ctx context.Context) *Client {
+	cp := *c
+	cp.ctx = ctx
+	return &cp
+}
+
+// context returns the client's context or the
+// background context if the client has none
+func (c *Client) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// logger returns the client's logger or a disabled
+// logger if the client has none
+func (c *Client) logger() *zerolog.Logger {
+	if c.Logger == nil {
+		return &nop
+	}
+	return c.Logger
+}
+
 // get sends a GET request as specified in the do method
 func (c *Client) get(domain, board, endpoint string, lastAccessed time.Time) (*http.Response, time.Time, error) {
 	return c.do("GET", domain, board, endpoint, lastAccessed)
@@ -125,8 +181,14 @@ func (c *Client) get(domain, board, endpoint string, lastAccessed time.Time) (*h
 
 // url formats the request url
 func (c *Client) url(domain, board, endpoint string) string {
+	return formatURL(c.SSL, domain, board, endpoint)
+}
+
+// formatURL formats the url of the endpoint on the domain,
+// the board may be empty for endpoints without a board
+func formatURL(ssl bool, domain, board, endpoint string) string {
 	scheme := "http"
-	if c.SSL {
+	if ssl {
 		scheme = "https"
 	}
 
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -17,6 +18,28 @@ const (
 
 )
 
+// refreshable is implemented by every resource which can be refreshed
+type refreshable interface {
+	mod() *modTime
+}
+
+// refresh requests the resource again using its modified time in the If-Modified-Since
+// header. Every Refresh method has the same contract:
+//   - If the resource changed then the new resource is returned with true and the
+//     old resource's modified time is updated so refreshing it again is a 304
+//   - If the resource didn't change, i.e. a 304, then nil is returned with false
+//   - If an error occurs, including a decoding error, then nil is returned with
+//     false and the error, the old resource isn't changed
+func refresh[T refreshable](old T, get func(since time.Time) (T, bool, error)) (T, bool, error) {
+	var zero T
+	new, mod, err := get(old.mod().time())
+	if err != nil || !mod {
+		return zero, false, err
+	}
+	*old.mod() = *new.mod()
+	return new, true, nil
+}
+
 // boards.json
 
 func (c *Client) GetBoards() (*Boards, bool, error) {
@@ -43,16 +66,14 @@ func (c *Client) getBoards(t time.Time) (*Boards, bool, error) {
 		return nil, false, err
 	}
 
-	b.modTime = modTime(mt)
+	b.modTime = newModTime(mt)
 	return &b, true, nil
 }
 
 func (c *Client) RefreshBoards(b *Boards) (*Boards, bool, error) {
-	boards, mod, err := c.getBoards(b.modTime.time())
-	if err == nil && mod {
-		b.modTime = boards.modTime
-	}
-	return boards, mod, err
+	return refresh(b, func(since time.Time) (*Boards, bool, error) {
+		return c.getBoards(since)
+	})
 }
 
 // threads.json
@@ -62,6 +83,10 @@ func (c *Client) GetThreads(board string) (*ThreadList, bool, error) {
 }
 
 func (c *Client) getThreads(board string, t time.Time) (*ThreadList, bool, error) {
+	if err := c.checkBoard(board); err != nil {
+		return nil, false, err
+	}
+
 	resp, mt, err := c.get(ApiDomain, board, threadListEndpoint, t)
 	if err != nil {
 		return nil, false, err
@@ -72,28 +97,26 @@ func (c *Client) getThreads(board string, t time.Time) (*ThreadList, bool, error
 	}
 
 	tl := &ThreadList{
-		modTime: modTime(mt),
+		modTime: newModTime(mt),
 		Board:   strings.Trim(board, "/"),
 	}
 
 	buf, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, true, err
+		return nil, false, err
 	}
 	err = json.Unmarshal(buf, &tl.Pages)
 	if err != nil {
-		return nil, true, err
+		return nil, false, err
 	}
 
 	return tl, true, nil
 }
 
 func (c *Client) RefreshThreads(tl *ThreadList) (*ThreadList, bool, error) {
-	threadlist, mod, err := c.getThreads(tl.Board, tl.modTime.time())
-	if err == nil && mod {
-		tl.modTime = threadlist.modTime
-	}
-	return threadlist, mod, err
+	return refresh(tl, func(since time.Time) (*ThreadList, bool, error) {
+		return c.getThreads(tl.Board, since)
+	})
 }
 
 // catalog.json
@@ -103,6 +126,10 @@ func (c *Client) GetCatalog(board string) (*Catalog, bool, error) {
 }
 
 func (c *Client) getCatalog(board string, t time.Time) (*Catalog, bool, error) {
+	if err := c.checkBoard(board); err != nil {
+		return nil, false, err
+	}
+
 	resp, mt, err := c.get(ApiDomain, board, catalogEndpoint, t)
 	if err != nil {
 		return nil, false, err
@@ -113,17 +140,17 @@ func (c *Client) getCatalog(board string, t time.Time) (*Catalog, bool, error) {
 	}
 
 	ctl := &Catalog{
-		modTime: modTime(mt),
+		modTime: newModTime(mt),
 		Board:   strings.Trim(board, "/"),
 	}
 
 	buf, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, true, err
+		return nil, false, err
 	}
 	err = json.Unmarshal(buf, &ctl.Pages)
 	if err != nil {
-		return nil, true, err
+		return nil, false, err
 	}
 
 	for i := range ctl.Pages {
@@ -137,11 +164,9 @@ func (c *Client) getCatalog(board string, t time.Time) (*Catalog, bool, error) {
 }
 
 func (c *Client) RefreshCatalog(ctl *Catalog) (*Catalog, bool, error) {
-	catalog, mod, err := c.getCatalog(ctl.Board, ctl.modTime.time())
-	if err == nil && mod {
-		ctl.modTime = catalog.modTime
-	}
-	return catalog, mod, err
+	return refresh(ctl, func(since time.Time) (*Catalog, bool, error) {
+		return c.getCatalog(ctl.Board, since)
+	})
 }
 
 // archive.json
@@ -151,6 +176,10 @@ func (c *Client) GetArchive(board string) (*Archive, bool, error) {
 }
 
 func (c *Client) getArchive(board string, t time.Time) (*Archive, bool, error) {
+	if err := c.checkBoard(board); err != nil {
+		return nil, false, err
+	}
+
 	resp, mt, err := c.get(ApiDomain, board, archiveEndpoint, t)
 	if err != nil {
 		return nil, false, err
@@ -163,15 +192,15 @@ func (c *Client) getArchive(board string, t time.Time) (*Archive, bool, error) {
 	var p []int
 	buf, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, true, err
+		return nil, false, err
 	}
 	err = json.Unmarshal(buf, &p)
 	if err != nil {
-		return nil, true, err
+		return nil, false, err
 	}
 
 	a := &Archive{
-		modTime: modTime(mt),
+		modTime: newModTime(mt),
 		Board:   strings.Trim(board, "/"),
 		PostIDs: p,
 	}
@@ -180,11 +209,9 @@ func (c *Client) getArchive(board string, t time.Time) (*Archive, bool, error) {
 }
 
 func (c *Client) RefreshArchive(a *Archive) (*Archive, bool, error) {
-	archive, mod, err := c.getArchive(a.Board, a.modTime.time())
-	if err == nil && mod {
-		a.modTime = archive.modTime
-	}
-	return archive, mod, err
+	return refresh(a, func(since time.Time) (*Archive, bool, error) {
+		return c.getArchive(a.Board, since)
+	})
 }
 
 // [board]/[1-15].json
@@ -197,6 +224,9 @@ func (c *Client) getPage(board string, page int, t time.Time) (*Page, bool, erro
 	if page < 1 || page > 15 {
 		return nil, false, fmt.Errorf("invalid page num, should be in the range 1-15 inclusive")
 	}
+	if err := c.checkBoard(board); err != nil {
+		return nil, false, err
+	}
 
 	resp, mt, err := c.get(ApiDomain, board, strconv.Itoa(page)+".json", t)
 	if err != nil {
@@ -210,36 +240,86 @@ func (c *Client) getPage(board string, page int, t time.Time) (*Page, bool, erro
 	var p Page
 	buf, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, true, err
+		return nil, false, err
 	}
 	err = json.Unmarshal(buf, &p)
 	if err != nil {
-		return nil, true, err
+		return nil, false, err
 	}
 
 	p.No = page
 	p.Board = strings.Trim(board, "/")
-	p.modTime = modTime(mt)
+	p.modTime = newModTime(mt)
 
 	for i := range p.Threads {
 		for j := range p.Threads[i].Posts {
 			p.Threads[i].Posts[j].Board = p.Board
 			if p.Threads[i].Posts[j].Filesize > 0 {
 				p.Threads[i].Posts[j].HasFile = true
-				p.Threads[i].addThreadAttributes(modTime(mt))
 			}
 		}
+		if len(p.Threads[i].Posts) > 0 {
+			p.Threads[i].addThreadAttributes(newModTime(mt))
+			p.Threads[i].Board = p.Board
+		}
 	}
 
 	return &p, true, nil
 }
 
 func (c *Client) RefreshPage(p *Page) (*Page, bool, error) {
-	page, mod, err := c.getPage(p.Board, p.No, p.modTime.time())
-	if err == nil && mod {
-		p.modTime = page.modTime
+	return refresh(p, func(since time.Time) (*Page, bool, error) {
+		return c.getPage(p.Board, p.No, since)
+	})
+}
+
+// ErrStopWalk can be returned by the function given to WalkBoard to stop walking without an error
+var ErrStopWalk = fmt.Errorf("stop walking board")
+
+// WalkBoard calls fn for every thread on the board's index pages in the order
+// they appear, the threads only contain the OP and its preview replies. The
+// number of pages is taken from boards.json and threads which appear on multiple
+// pages, e.g. stickies or threads bumped during the walk, are only passed to fn
+// once. Walking stops if the context is done or if fn returns an error, in which
+// case the error is returned unless it's ErrStopWalk
+func (c *Client) WalkBoard(ctx context.Context, board string, fn func(t *Thread) error) error {
+	b, err := c.Board(board)
+	if err != nil {
+		return err
+	}
+
+	c = c.WithContext(ctx)
+	seen := make(map[int]struct{})
+	for page := 1; page <= b.Pages; page++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		p, _, err := c.GetPage(board, page)
+		if err == ErrNotFound {
+			// The board has fewer pages than boards.json says
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		for i := range p.Threads {
+			t := &p.Threads[i]
+			if _, found := seen[t.No]; found || len(t.Posts) == 0 {
+				continue
+			}
+			seen[t.No] = struct{}{}
+
+			err := fn(t)
+			if err == ErrStopWalk {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
 	}
-	return page, mod, err
+
+	return nil
 }
 
 // [board]/thread/[op ID].json
@@ -249,6 +329,10 @@ func (c *Client) GetThread(board string, opID int) (*Thread, bool, error) {
 }
 
 func (c *Client) getThread(board string, opID int, t time.Time) (*Thread, bool, error) {
+	if err := c.checkBoard(board); err != nil {
+		return nil, false, err
+	}
+
 	resp, mt, err := c.get(ApiDomain, board, fmt.Sprintf("thread/%d.json", opID), t)
 	if err != nil {
 		return nil, false, err
@@ -261,11 +345,11 @@ func (c *Client) getThread(board string, opID int, t time.Time) (*Thread, bool,
 	var p Thread
 	buf, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, true, err
+		return nil, false, err
 	}
 	err = json.Unmarshal(buf, &p)
 	if err != nil {
-		return nil, true, err
+		return nil, false, err
 	}
 
 	for i := range p.Posts {
@@ -276,16 +360,14 @@ func (c *Client) getThread(board string, opID int, t time.Time) (*Thread, bool,
 
 	}
 
-	p.addThreadAttributes(modTime(mt))
+	p.addThreadAttributes(newModTime(mt))
 	p.Board = strings.Trim(board, "/")
 
 	return &p, true, nil
 }
 
 func (c *Client) RefreshThread(th *Thread) (*Thread, bool, error) {
-	thread, mod, err := c.getThread(th.Board, th.No, th.modTime.time())
-	if err == nil && mod {
-		th.modTime = thread.modTime
-	}
-	return thread, mod, err
+	return refresh(th, func(since time.Time) (*Thread, bool, error) {
+		return c.getThread(th.Board, th.No, since)
+	})
 }
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long boards.json is cached before it's refreshed
+const boardsTTL = time.Hour
+
+// UnknownBoardError is returned when a request is made to a board which isn't in boards.json
+type UnknownBoardError struct {
+	Board string
+}
+
+func (e *UnknownBoardError) Error() string {
+	return fmt.Sprintf("unknown board: %s", e.Board)
+}
+
+// boardCache caches boards.json so boards can be validated without a request
+// each time, it's a pointer in the Client so copies of the client share it
+type boardCache struct {
+	mu      sync.Mutex
+	boards  *Boards
+	byName  map[string]*Board
+	updated time.Time     // When boards.json was last fetched or refreshed
+	loading chan struct{} // Closed once boards.json has been fetched, nil if it isn't being fetched
+	err     error         // The error which occurred when boards.json was last fetched
+}
+
+// Board returns the metadata of the board, e.g. its bump limit or whether it
+// has flags. boards.json is loaded the first time this is called and is cached
+// for an hour after which it's refreshed. An *UnknownBoardError is returned if
+// the board doesn't exist
+func (c *Client) Board(name string) (*Board, error) {
+	name = strings.Trim(name, "/")
+
+	cache := c.boards
+	err := c.loadBoards(cache)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.boards == nil {
+		return nil, fmt.Errorf("failed to load boards: %w", err)
+	}
+
+	b, found := cache.byName[name]
+	if !found {
+		return nil, &UnknownBoardError{Board: name}
+	}
+	return b, nil
+}
+
+// loadBoards fetches boards.json if it isn't cached otherwise it refreshes
+// it once it's stale. Only one caller fetches boards.json at a time and the
+// request is made without the cache locked, while it's being refreshed other
+// callers use the stale boards and if nothing is cached they wait for it
+func (c *Client) loadBoards(cache *boardCache) error {
+	cache.mu.Lock()
+	if cache.boards != nil && time.Since(cache.updated) <= boardsTTL {
+		cache.mu.Unlock()
+		return nil
+	}
+	if cache.loading != nil {
+		loading, stale := cache.loading, cache.boards != nil
+		cache.mu.Unlock()
+		if stale {
+			return nil
+		}
+
+		<-loading
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return cache.err
+	}
+	cache.loading = make(chan struct{})
+	old := cache.boards
+	cache.mu.Unlock()
+
+	var boards *Boards
+	var mod bool
+	var err error
+	if old == nil {
+		boards, mod, err = c.GetBoards()
+	} else {
+		boards, mod, err = c.RefreshBoards(old)
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	close(cache.loading)
+	cache.loading = nil
+	cache.err = err
+	if err != nil {
+		// Stale metadata is better than none
+		if old != nil {
+			c.logger().Warn().Err(err).Msg("failed to refresh boards, using cached boards")
+		}
+		return err
+	}
+
+	cache.updated = time.Now()
+	if !mod {
+		return nil
+	}
+	cache.boards = boards
+	cache.byName = make(map[string]*Board, len(boards.Boards))
+	for i := range boards.Boards {
+		cache.byName[boards.Boards[i].Board] = &boards.Boards[i]
+	}
+	return nil
+}
+
+// checkBoard returns an error if the board doesn't exist
+func (c *Client) checkBoard(board string) error {
+	_, err := c.Board(board)
+	return err
+}
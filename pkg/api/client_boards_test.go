@@ -0,0 +1,186 @@
+package api
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// transportFunc serves requests without using the network
+type transportFunc func(r *http.Request) *http.Response
+
+func (f transportFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r), nil
+}
+
+// fakeClient returns a client whose api requests are served by the
+// routes, keyed by the URL path, unknown paths return a 404
+func fakeClient(routes map[string]string, requests *int64) *Client {
+	c := NewClient(1000, 1000, true, true)
+	c.api.Transport = transportFunc(func(r *http.Request) *http.Response {
+		atomic.AddInt64(requests, 1)
+		body, found := routes[r.URL.Path]
+		status := http.StatusOK
+		if !found {
+			status = http.StatusNotFound
+		}
+		return &http.Response{
+			StatusCode: status,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+			Request:    r,
+		}
+	})
+	return c
+}
+
+const boardsFixture = `{"boards": [
+	{"board": "po", "title": "Papercraft & Origami", "bump_limit": 300, "image_limit": 150, "max_filesize": 4096, "max_webm_filesize": 3072},
+	{"board": "pol", "title": "Politically Incorrect", "country_flags": 1, "user_ids": 1, "board_flags": {"AN": "Anarchist"}}
+]}`
+
+func TestBoardCache(t *testing.T) {
+	var requests int64
+	c := fakeClient(map[string]string{
+		"/boards.json":             boardsFixture,
+		"/po/thread/570368.json":   `{"posts": [{"no": 570368, "replies": 120, "images": 200}]}`,
+		"/nope/thread/570368.json": `{"posts": [{"no": 570368}]}`,
+	}, &requests)
+
+	b, err := c.Board("/po/")
+	if err != nil {
+		t.Errorf("failed to get board: %s\n", err)
+		return
+	}
+	if b.Title != "Papercraft & Origami" {
+		t.Errorf("wrong board returned: %+v\n", b)
+	}
+
+	// Copies of the client share the cache so boards.json is only requested once
+	cp := *c
+	if _, err := cp.Board("pol"); err != nil {
+		t.Errorf("failed to get board: %s\n", err)
+	}
+	if requests != 1 {
+		t.Errorf("boards.json requested %d times but expected once\n", requests)
+	}
+
+	// Unknown boards return a typed error before any request is made
+	_, _, err = c.GetThread("nope", 570368)
+	var unknown *UnknownBoardError
+	if !errors.As(err, &unknown) || unknown.Board != "nope" {
+		t.Errorf("expected unknown board error but got: %v\n", err)
+	}
+	if requests != 1 {
+		t.Errorf("request made to an unknown board\n")
+	}
+
+	thread, _, err := c.GetThread("po", 570368)
+	if err != nil {
+		t.Errorf("failed to get thread: %s\n", err)
+		return
+	}
+	if thread.BumpsLeft(b) != 180 || thread.ImagesLeft(b) != 0 {
+		t.Errorf("wrong limits, bumps left: %d, images left: %d\n", thread.BumpsLeft(b), thread.ImagesLeft(b))
+	}
+}
+
+func TestBoardCacheRefresh(t *testing.T) {
+	var requests int64
+	c := fakeClient(map[string]string{"/boards.json": boardsFixture}, &requests)
+	if _, err := c.Board("po"); err != nil {
+		t.Fatalf("failed to get board: %s\n", err)
+	}
+
+	// Make the cache stale and block the refresh of boards.json
+	c.boards.mu.Lock()
+	c.boards.updated = time.Now().Add(-2 * boardsTTL)
+	c.boards.mu.Unlock()
+	started, release := make(chan struct{}), make(chan struct{})
+	c.api.Transport = transportFunc(func(r *http.Request) *http.Response {
+		close(started)
+		<-release
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(boardsFixture)),
+			Header:     make(http.Header),
+			Request:    r,
+		}
+	})
+
+	refreshed := make(chan error)
+	go func() {
+		_, err := c.Board("po")
+		refreshed <- err
+	}()
+	<-started
+
+	// Other callers use the stale boards while the refresh is in progress
+	found := make(chan error)
+	go func() {
+		_, err := c.Board("pol")
+		found <- err
+	}()
+	select {
+	case err := <-found:
+		if err != nil {
+			t.Errorf("failed to get board from stale cache: %s\n", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("waited for boards.json to be refreshed instead of using the stale cache\n")
+	}
+
+	close(release)
+	if err := <-refreshed; err != nil {
+		t.Errorf("failed to refresh boards: %s\n", err)
+	}
+	c.boards.mu.Lock()
+	defer c.boards.mu.Unlock()
+	if time.Since(c.boards.updated) > time.Minute {
+		t.Errorf("boards.json wasn't refreshed\n")
+	}
+}
+
+func TestBoardCapabilities(t *testing.T) {
+	var requests int64
+	c := fakeClient(map[string]string{"/boards.json": boardsFixture}, &requests)
+	po, err := c.Board("po")
+	if err != nil {
+		t.Errorf("failed to get board: %s\n", err)
+		return
+	}
+	pol, err := c.Board("pol")
+	if err != nil {
+		t.Errorf("failed to get board: %s\n", err)
+		return
+	}
+
+	if po.HasFlags() || !pol.HasFlags() {
+		t.Errorf("wrong flag capabilities, po: %v, pol: %v\n", po.HasFlags(), pol.HasFlags())
+	}
+	if po.FileSizeLimit(".jpg") != 4096*1024 || po.FileSizeLimit(".webm") != 3072*1024 {
+		t.Errorf("wrong file size limits: %d %d\n", po.FileSizeLimit(".jpg"), po.FileSizeLimit(".webm"))
+	}
+
+	p := &Post{ID: "abc", BoardFlag: "AN", FlagName: "Anarchy", CountryName: "Britain"}
+	if _, ok := p.PosterID(po); ok {
+		t.Errorf("poster id returned for board without ids\n")
+	}
+	if id, ok := p.PosterID(pol); !ok || id != "abc" {
+		t.Errorf("wrong poster id: %s\n", id)
+	}
+	if _, ok := p.Flag(po); ok {
+		t.Errorf("flag returned for board without flags\n")
+	}
+	if name, ok := p.Flag(pol); !ok || name != "Anarchist" {
+		t.Errorf("wrong flag: %s\n", name)
+	}
+	p.BoardFlag = ""
+	if name, _ := p.Flag(pol); name != "Britain" {
+		t.Errorf("wrong country flag: %s\n", name)
+	}
+}
@@ -23,6 +23,7 @@ type Media struct {
 	Filename string        // Filename of the image if applicable, only works ig FromPost() method is used
 	URL      string        // URL to the image resource
 	MD5      string        // Base64 encoded MD5 hash of the response content
+	Size     int64         // Size of the response content in bytes
 }
 
 func (c *Client) getFileFromID(id, ext, filename, domain, board, endpoint string) (*Media, error) {
@@ -33,38 +34,39 @@ func (c *Client) getFileFromID(id, ext, filename, domain, board, endpoint string
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
-	hash := md5.Sum(data)
 	media := Media{
 		Body:     ioutil.NopCloser(bytes.NewReader(data)),
 		Filename: filename,
 		ID:       id,
 		Ext:      ext,
 		URL:      c.url(domain, board, endpoint),
-		MD5:      base64.StdEncoding.EncodeToString(hash[:]),
+		MD5:      hashMD5(data),
+		Size:     int64(len(data)),
 	}
 
 	return &media, nil
 }
 
 func (c *Client) GetFile(p *Post) (*Media, error) {
-	return c.getFileFromID(p.ImageID.String(), p.Ext, p.Filename, MediaDomainA, p.Board, p.ImageID.String()+p.Ext)
+	return c.getFileFromID(p.ImageID.String(), p.Ext, p.Filename, MediaDomainA, p.Board, p.fileEndpoint())
 }
 
 func (c *Client) GetThumbnail(p *Post) (*Media, error) {
-	return c.getFileFromID(p.ImageID.String()+"s", ".jpg", p.Filename+"-s", MediaDomainA, p.Board, p.ImageID.String()+"s.jpg")
+	return c.getFileFromID(p.ImageID.String()+"s", ".jpg", p.Filename+"-s", MediaDomainA, p.Board, p.thumbnailEndpoint())
 }
 
 func (c *Client) GetFlag(flagCode string) (*Media, error) {
-	return c.getFileFromID(flagCode, ".gif", flagCode, StaticDomain, "image/country", flagCode+".gif")
+	return c.getFileFromID(flagCode, ".gif", flagCode, StaticDomain, countryFlagDir, flagCode+".gif")
 }
 
 func (c *Client) GetTrollFlag(flagCode string) (*Media, error) {
-	return c.getFileFromID(flagCode, ".gif", flagCode, StaticDomain, "image/country/troll", flagCode+".gif")
+	return c.getFileFromID(flagCode, ".gif", flagCode, StaticDomain, trollFlagDir, flagCode+".gif")
 }
 
 func (c *Client) GetCustomSpoiler(board string, num int) (*Media, error) {
@@ -72,7 +74,7 @@ func (c *Client) GetCustomSpoiler(board string, num int) (*Media, error) {
 		return nil, ErrInvalidSpoilerNum
 	}
 
-	spoiler := fmt.Sprintf("spoiler-%s%d", board, num)
+	spoiler := customSpoiler(board, num)
 	return c.getFileFromID(spoiler, ".png", spoiler, StaticDomain, "image/", spoiler+".png")
 
 }
@@ -97,3 +99,19 @@ func (c *Client) GetStaticAsset(endpoint string) (*Media, error) {
 func VerifyMD5(p *Post, m *Media) bool {
 	return p.MD5 == m.MD5
 }
+
+// VerifyFileMD5 hashes the contents of r, e.g. a saved file, in the same way
+// as downloaded media and returns whether the hash matches the post's MD5
+func VerifyFileMD5(p *Post, r io.Reader) (bool, error) {
+	h := md5.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return false, err
+	}
+	return VerifyMD5(p, &Media{MD5: base64.StdEncoding.EncodeToString(h.Sum(nil))}), nil
+}
+
+// hashMD5 returns the base64 encoded MD5 hash of the data, this is the format used by Post.MD5
+func hashMD5(data []byte) string {
+	hash := md5.Sum(data)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -88,6 +89,20 @@ func TestMD5Hash(t *testing.T) {
 	}
 }
 
+func TestVerifyFileMD5(t *testing.T) {
+	// The base64 encoded MD5 hash of "crow"
+	post := &Post{MD5: "u71T6ROkBLBKvzc9wdrEmw=="}
+
+	ok, err := VerifyFileMD5(post, strings.NewReader("crow"))
+	if err != nil || !ok {
+		t.Errorf("MD5 hash of matching file not recognised as correct, err: %v\n", err)
+	}
+	ok, err = VerifyFileMD5(post, strings.NewReader("corrupt"))
+	if err != nil || ok {
+		t.Errorf("MD5 hash of corrupt file recognised as correct, err: %v\n", err)
+	}
+}
+
 func TestMediaRequests(t *testing.T) {
 	board := "/po/"
 	threadNum := 570368
@@ -0,0 +1,294 @@
+package api
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// NodeType is the type of a node in a parsed comment
+type NodeType int
+
+const (
+	TextNode           NodeType = iota // Plain text
+	LineBreakNode                      // A line break, i.e. <br>
+	QuoteLinkNode                      // A link to a post on the same board, e.g. >>570400
+	CrossBoardLinkNode                 // A link to a board, a board's catalog or a post on another board, e.g. >>>/g/ or >>>/g/570400
+	GreentextNode                      // Quoted text, e.g. >implying
+	SpoilerNode                        // Spoilered text, i.e. [spoiler]
+	CodeNode                           // A code block, i.e. [code]
+	DeadLinkNode                       // A link to a post which has been deleted or pruned
+	ExifNode                           // A table of the EXIF data of the post's image
+)
+
+func (t NodeType) String() string {
+	switch t {
+	case TextNode:
+		return "text"
+	case LineBreakNode:
+		return "line_break"
+	case QuoteLinkNode:
+		return "quote_link"
+	case CrossBoardLinkNode:
+		return "cross_board_link"
+	case GreentextNode:
+		return "greentext"
+	case SpoilerNode:
+		return "spoiler"
+	case CodeNode:
+		return "code"
+	case DeadLinkNode:
+		return "dead_link"
+	case ExifNode:
+		return "exif"
+	default:
+		return "unknown"
+	}
+}
+
+// ExifField is a single row of a post's EXIF table
+type ExifField struct {
+	Key   string
+	Value string
+}
+
+// CommentNode is a node in a parsed comment. Links to the post's own thread
+// only contain a Post, these can be resolved using Post.ParseComment()
+type CommentNode struct {
+	Type     NodeType
+	Text     string         // The text, for links this is the link text, e.g. ">>570400", for code blocks it's the code
+	Board    string         // The board linked to, if applicable
+	Thread   int            // The thread linked to, zero if unknown or if the link is to a board
+	Post     int            // The post linked to, zero if the link is to a board
+	Search   string         // The catalog search term for links such as >>>/g/search
+	Children []*CommentNode // The contents of greentext and spoilers
+	Exif     []ExifField    // The rows of EXIF tables
+}
+
+// ParseComment parses a post's HTML comment into a list of nodes.
+// Links to posts in the same thread don't have their Board and
+// Thread set, use Post.ParseComment() to resolve these
+func ParseComment(comment string) ([]*CommentNode, error) {
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(comment), root)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := make([]*CommentNode, 0)
+	for _, n := range nodes {
+		parsed = append(parsed, parseCommentNode(n)...)
+	}
+	return mergeText(parsed), nil
+}
+
+// ParseComment parses the post's comment, links to posts in
+// the same thread have their Board and Thread resolved
+func (p *Post) ParseComment() ([]*CommentNode, error) {
+	nodes, err := ParseComment(p.Comment)
+	if err != nil {
+		return nil, err
+	}
+
+	thread := p.RepliesTo
+	if thread == 0 {
+		thread = p.No
+	}
+	WalkComment(nodes, func(n *CommentNode) {
+		if (n.Type == QuoteLinkNode || n.Type == DeadLinkNode) && n.Board == "" {
+			n.Board = p.Board
+			// Dead links could be to any thread so only quote links are resolved
+			if n.Type == QuoteLinkNode && n.Thread == 0 {
+				n.Thread = thread
+			}
+		}
+	})
+	return nodes, nil
+}
+
+// WalkComment calls fn for every node and its children in depth-first order
+func WalkComment(nodes []*CommentNode, fn func(n *CommentNode)) {
+	for _, n := range nodes {
+		fn(n)
+		WalkComment(n.Children, fn)
+	}
+}
+
+func parseCommentNode(n *html.Node) []*CommentNode {
+	switch n.Type {
+	case html.TextNode:
+		return []*CommentNode{{Type: TextNode, Text: n.Data}}
+	case html.ElementNode:
+	default:
+		return nil
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return []*CommentNode{{Type: LineBreakNode}}
+	case atom.Wbr:
+		return nil
+	case atom.S:
+		return []*CommentNode{{Type: SpoilerNode, Children: parseChildren(n)}}
+	case atom.Pre:
+		return []*CommentNode{{Type: CodeNode, Text: nodeText(n)}}
+	case atom.Table:
+		if hasClass(n, "exif") {
+			return []*CommentNode{{Type: ExifNode, Exif: parseExif(n)}}
+		}
+	case atom.A:
+		if hasClass(n, "quotelink") {
+			return []*CommentNode{parseLink(nodeText(n), attr(n, "href"))}
+		}
+	case atom.Span:
+		switch {
+		case hasClass(n, "quote"):
+			return []*CommentNode{{Type: GreentextNode, Children: parseChildren(n)}}
+		case hasClass(n, "deadlink"):
+			link := parseLink(nodeText(n), "")
+			link.Type = DeadLinkNode
+			return []*CommentNode{link}
+		case hasClass(n, "abbr"):
+			// Notices such as "EXIF data available" which rely on javascript
+			return nil
+		}
+	}
+
+	// Unknown elements such as formatting are replaced by their contents
+	return parseChildren(n)
+}
+
+func parseChildren(n *html.Node) []*CommentNode {
+	nodes := make([]*CommentNode, 0)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nodes = append(nodes, parseCommentNode(c)...)
+	}
+	return mergeText(nodes)
+}
+
+// parseLink creates a link node from the link's text, e.g. ">>>/g/123",
+// and its href, e.g. "/g/thread/123#p123", the href is preferred
+// since it contains the thread of the post
+func parseLink(text, href string) *CommentNode {
+	n := &CommentNode{Type: QuoteLinkNode, Text: text}
+	if strings.HasPrefix(text, ">>>") {
+		n.Type = CrossBoardLinkNode
+
+		// The text is either ">>>/board/", ">>>/board/123" or ">>>/board/search"
+		parts := strings.SplitN(strings.Trim(strings.TrimPrefix(text, ">>>"), "/"), "/", 2)
+		n.Board = parts[0]
+		if len(parts) == 2 {
+			if no, err := strconv.Atoi(parts[1]); err == nil {
+				n.Post = no
+			} else {
+				n.Search = parts[1]
+			}
+		}
+	} else if no, err := strconv.Atoi(strings.TrimPrefix(text, ">>")); err == nil {
+		n.Post = no
+	}
+
+	if href == "" {
+		return n
+	}
+
+	// Links to posts have the form "#p123" or "/board/thread/123#p456"
+	// and links to catalog searches have the form "/board/catalog#s=search"
+	if l, err := ParseLink(href); err == nil {
+		if l.Board != "" {
+			n.Board = l.Board
+		}
+		if l.Thread != 0 {
+			n.Thread = l.Thread
+		}
+		if l.Post != 0 {
+			n.Post = l.Post
+		}
+	}
+	if u, err := url.Parse(href); err == nil && strings.HasPrefix(u.Fragment, "s=") {
+		n.Search = u.Fragment[2:]
+	}
+	return n
+}
+
+func parseExif(n *html.Node) []ExifField {
+	fields := make([]ExifField, 0)
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Tr {
+			cells := make([]string, 0, 2)
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && c.DataAtom == atom.Td {
+					cells = append(cells, strings.TrimSpace(nodeText(c)))
+				}
+			}
+			// Rows with a single cell are section headings
+			if len(cells) == 2 {
+				fields = append(fields, ExifField{Key: cells[0], Value: cells[1]})
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	return fields
+}
+
+// mergeText joins adjacent text nodes together
+func mergeText(nodes []*CommentNode) []*CommentNode {
+	merged := make([]*CommentNode, 0, len(nodes))
+	for _, n := range nodes {
+		if last := len(merged) - 1; n.Type == TextNode && last >= 0 && merged[last].Type == TextNode {
+			merged[last].Text += n.Text
+			continue
+		}
+		merged = append(merged, n)
+	}
+	return merged
+}
+
+// nodeText returns the text within the node where line breaks are newlines
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && n.DataAtom == atom.Br:
+			b.WriteString("\n")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
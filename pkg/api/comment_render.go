@@ -0,0 +1,143 @@
+package api
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// PlainText returns the post's comment as plain text. Line breaks become
+// newlines, entities are unescaped and formatting such as spoilers is removed
+func (p *Post) PlainText() string {
+	nodes, err := p.ParseComment()
+	if err != nil {
+		return html.UnescapeString(tagRegex.ReplaceAllString(p.Comment, ""))
+	}
+	return RenderText(nodes)
+}
+
+// Markdown returns the post's comment formatted as Markdown
+func (p *Post) Markdown() string {
+	nodes, err := p.ParseComment()
+	if err != nil {
+		return escapeMarkdown(html.UnescapeString(tagRegex.ReplaceAllString(p.Comment, "")), true)
+	}
+	return RenderMarkdown(nodes)
+}
+
+// Used to strip tags if the comment can't be parsed
+var tagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// RenderText renders the nodes as plain text
+func RenderText(nodes []*CommentNode) string {
+	var b strings.Builder
+	for i, n := range nodes {
+		// Blocks are always on their own lines
+		if i > 0 && isBlock(nodes[i-1]) && n.Type != LineBreakNode {
+			b.WriteString("\n")
+		}
+
+		switch n.Type {
+		case LineBreakNode:
+			b.WriteString("\n")
+		case GreentextNode, SpoilerNode:
+			b.WriteString(RenderText(n.Children))
+		case CodeNode:
+			if !atLineStart(&b) {
+				b.WriteString("\n")
+			}
+			b.WriteString(n.Text)
+		case ExifNode:
+			for i, f := range n.Exif {
+				if i > 0 {
+					b.WriteString("\n")
+				}
+				b.WriteString(f.Key + ": " + f.Value)
+			}
+		default:
+			b.WriteString(n.Text)
+		}
+	}
+	return b.String()
+}
+
+// RenderMarkdown renders the nodes as Markdown. Greentext becomes a
+// quote, spoilers use the ||spoiler|| syntax, code blocks are fenced
+// and links are kept as >>123 references
+func RenderMarkdown(nodes []*CommentNode) string {
+	var b strings.Builder
+	renderMarkdown(&b, nodes)
+	return b.String()
+}
+
+func renderMarkdown(b *strings.Builder, nodes []*CommentNode) {
+	for i, n := range nodes {
+		// Blocks are always on their own lines
+		if i > 0 && isBlock(nodes[i-1]) && n.Type != LineBreakNode {
+			b.WriteString("\n")
+		}
+
+		switch n.Type {
+		case LineBreakNode:
+			b.WriteString("\n")
+		case GreentextNode:
+			// The greentext's own ">" is replaced by the quote marker
+			if atLineStart(b) {
+				b.WriteString("> ")
+				text := RenderText(n.Children)
+				b.WriteString(escapeMarkdown(strings.TrimPrefix(text, ">"), false))
+			} else {
+				renderMarkdown(b, n.Children)
+			}
+		case SpoilerNode:
+			b.WriteString("||")
+			renderMarkdown(b, n.Children)
+			b.WriteString("||")
+		case CodeNode:
+			if !atLineStart(b) {
+				b.WriteString("\n")
+			}
+			b.WriteString("```\n" + n.Text + "\n```")
+		case ExifNode:
+			if !atLineStart(b) {
+				b.WriteString("\n")
+			}
+			b.WriteString("| EXIF | |\n| --- | --- |")
+			for _, f := range n.Exif {
+				b.WriteString("\n| " + escapeMarkdown(f.Key, false) + " | " + escapeMarkdown(f.Value, false) + " |")
+			}
+		default:
+			b.WriteString(escapeMarkdown(n.Text, atLineStart(b)))
+		}
+	}
+}
+
+// Characters which are always escaped in Markdown
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `~`, `\~`,
+	`[`, `\[`, `]`, `\]`, `|`, `\|`, `<`, `\<`,
+)
+
+// escapeMarkdown escapes the text so it isn't formatted, if the text
+// starts a line then characters which begin quotes and headings are
+// also escaped
+func escapeMarkdown(s string, lineStart bool) string {
+	lines := strings.Split(markdownEscaper.Replace(s), "\n")
+	for i, l := range lines {
+		if (i > 0 || lineStart) && (strings.HasPrefix(l, ">") || strings.HasPrefix(l, "#")) {
+			lines[i] = `\` + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isBlock returns whether the node is rendered on its own lines
+func isBlock(n *CommentNode) bool {
+	return n.Type == CodeNode || n.Type == ExifNode
+}
+
+func atLineStart(b *strings.Builder) bool {
+	s := b.String()
+	return s == "" || strings.HasSuffix(s, "\n")
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseComment(t *testing.T) {
+	tests := []struct {
+		name     string
+		comment  string
+		expected []*CommentNode
+	}{
+		{
+			name:    "text and line breaks",
+			comment: `hello &amp; welcome<br>second<wbr>line`,
+			expected: []*CommentNode{
+				{Type: TextNode, Text: "hello & welcome"},
+				{Type: LineBreakNode},
+				{Type: TextNode, Text: "secondline"},
+			},
+		},
+		{
+			name:    "quote link to the same thread",
+			comment: `<a href="#p570400" class="quotelink">&gt;&gt;570400</a>`,
+			expected: []*CommentNode{
+				{Type: QuoteLinkNode, Text: ">>570400", Post: 570400},
+			},
+		},
+		{
+			name:    "quote link to another thread",
+			comment: `<a href="/po/thread/570368#p570400" class="quotelink">&gt;&gt;570400</a>`,
+			expected: []*CommentNode{
+				{Type: QuoteLinkNode, Text: ">>570400", Board: "po", Thread: 570368, Post: 570400},
+			},
+		},
+		{
+			name:    "cross board link to a post",
+			comment: `<a href="/g/thread/81234567#p81234599" class="quotelink">&gt;&gt;&gt;/g/81234599</a>`,
+			expected: []*CommentNode{
+				{Type: CrossBoardLinkNode, Text: ">>>/g/81234599", Board: "g", Thread: 81234567, Post: 81234599},
+			},
+		},
+		{
+			name:    "cross board link to a board",
+			comment: `<a href="//boards.4channel.org/g/" class="quotelink">&gt;&gt;&gt;/g/</a>`,
+			expected: []*CommentNode{
+				{Type: CrossBoardLinkNode, Text: ">>>/g/", Board: "g"},
+			},
+		},
+		{
+			name:    "cross board link to a catalog search",
+			comment: `<a href="//boards.4channel.org/g/catalog#s=sqt" class="quotelink">&gt;&gt;&gt;/g/sqt</a>`,
+			expected: []*CommentNode{
+				{Type: CrossBoardLinkNode, Text: ">>>/g/sqt", Board: "g", Search: "sqt"},
+			},
+		},
+		{
+			name:    "greentext with a link",
+			comment: `<span class="quote">&gt;implying <a href="#p1" class="quotelink">&gt;&gt;1</a></span>`,
+			expected: []*CommentNode{
+				{Type: GreentextNode, Children: []*CommentNode{
+					{Type: TextNode, Text: ">implying "},
+					{Type: QuoteLinkNode, Text: ">>1", Post: 1},
+				}},
+			},
+		},
+		{
+			name:    "spoiler with formatting",
+			comment: `<s>it was <b>him</b> all along</s>`,
+			expected: []*CommentNode{
+				{Type: SpoilerNode, Children: []*CommentNode{
+					{Type: TextNode, Text: "it was him all along"},
+				}},
+			},
+		},
+		{
+			name:    "code block",
+			comment: `<pre class="prettyprint">if (a &lt; b) {<br>    return;<br>}</pre>`,
+			expected: []*CommentNode{
+				{Type: CodeNode, Text: "if (a < b) {\n    return;\n}"},
+			},
+		},
+		{
+			name:    "dead links",
+			comment: `<span class="deadlink">&gt;&gt;123</span><span class="deadlink">&gt;&gt;&gt;/g/456</span>`,
+			expected: []*CommentNode{
+				{Type: DeadLinkNode, Text: ">>123", Post: 123},
+				{Type: DeadLinkNode, Text: ">>>/g/456", Board: "g", Post: 456},
+			},
+		},
+		{
+			name: "exif table",
+			comment: `nice<br><span class="abbr">[EXIF data available. <a href="javascript:void(0)" onclick="toggle('exif1')">Click here to show/hide.</a>]</span><br>` +
+				`<table class="exif" id="exif1"><tr><td colspan="2"><b>Camera-Specific Properties:</b></td></tr>` +
+				`<tr><td colspan="2"><b></b></td></tr><tr><td>Equipment Make</td><td>Canon</td></tr>` +
+				`<tr><td>Camera Model</td><td>Canon EOS 5D</td></tr></table>`,
+			expected: []*CommentNode{
+				{Type: TextNode, Text: "nice"},
+				{Type: LineBreakNode},
+				{Type: LineBreakNode},
+				{Type: ExifNode, Exif: []ExifField{
+					{Key: "Equipment Make", Value: "Canon"},
+					{Key: "Camera Model", Value: "Canon EOS 5D"},
+				}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		nodes, err := ParseComment(tc.comment)
+		if err != nil {
+			t.Errorf("%s: failed to parse comment: %s\n", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(nodes, tc.expected) {
+			t.Errorf("%s: parsed incorrectly\n got: %+v\nwant: %+v\n", tc.name, dumpNodes(nodes), dumpNodes(tc.expected))
+		}
+	}
+}
+
+func TestPostParseComment(t *testing.T) {
+	p := &Post{
+		Board:     "po",
+		No:        570400,
+		RepliesTo: 570368,
+		Comment:   `<a href="#p570368" class="quotelink">&gt;&gt;570368</a><span class="deadlink">&gt;&gt;570390</span>`,
+	}
+
+	nodes, err := p.ParseComment()
+	if err != nil {
+		t.Fatalf("failed to parse comment: %s\n", err)
+	}
+	if nodes[0].Board != "po" || nodes[0].Thread != 570368 || nodes[0].Post != 570368 {
+		t.Errorf("quote link not resolved to the post's thread: %+v\n", nodes[0])
+	}
+	if nodes[1].Board != "po" || nodes[1].Thread != 0 {
+		t.Errorf("dead link resolved incorrectly: %+v\n", nodes[1])
+	}
+}
+
+func dumpNodes(nodes []*CommentNode) []CommentNode {
+	d := make([]CommentNode, len(nodes))
+	for i, n := range nodes {
+		d[i] = *n
+	}
+	return d
+}
+
+func TestRenderComment(t *testing.T) {
+	p := &Post{
+		Board:     "g",
+		No:        2,
+		RepliesTo: 1,
+		Comment: `<a href="#p1" class="quotelink">&gt;&gt;1</a><br><span class="quote">&gt;using *nix</span><br>` +
+			`it&#039;s <s>fine</s><br><pre class="prettyprint">int main() {<br>  return 0;<br>}</pre>` +
+			`see <a href="/g/thread/3#p4" class="quotelink">&gt;&gt;&gt;/g/4</a>`,
+	}
+
+	text := "" +
+		">>1\n" +
+		">using *nix\n" +
+		"it's fine\n" +
+		"int main() {\n  return 0;\n}\n" +
+		"see >>>/g/4"
+	if s := p.PlainText(); s != text {
+		t.Errorf("plain text rendered incorrectly\n got: %q\nwant: %q\n", s, text)
+	}
+
+	markdown := "" +
+		"\\>>1\n" +
+		"> using \\*nix\n" +
+		"it's ||fine||\n" +
+		"```\nint main() {\n  return 0;\n}\n```\n" +
+		"see >>>/g/4"
+	if s := p.Markdown(); s != markdown {
+		t.Errorf("markdown rendered incorrectly\n got: %q\nwant: %q\n", s, markdown)
+	}
+}
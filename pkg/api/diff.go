@@ -0,0 +1,155 @@
+package api
+
+// ThreadDiff is the difference between two snapshots of a thread
+type ThreadDiff struct {
+	NewPosts        []*Post // Posts in the new thread which aren't in the old thread
+	DeletedPosts    []*Post // Posts in the old thread which aren't in the new thread
+	DeletedFiles    []*Post // Posts from the new thread whose file was deleted since the old thread
+	StickyChanged   bool    // Whether the thread was stickied or unstickied
+	ClosedChanged   bool    // Whether the thread was closed or reopened
+	ArchivedChanged bool    // Whether the thread was archived
+}
+
+// Empty returns whether nothing changed between the snapshots
+func (d *ThreadDiff) Empty() bool {
+	return len(d.NewPosts) == 0 && len(d.DeletedPosts) == 0 && len(d.DeletedFiles) == 0 &&
+		!d.StickyChanged && !d.ClosedChanged && !d.ArchivedChanged
+}
+
+// DiffThread compares two snapshots of the same thread, e.g. the thread
+// passed to Client.RefreshThread() and the thread it returns
+func DiffThread(old, new *Thread) *ThreadDiff {
+	d := &ThreadDiff{
+		NewPosts:        make([]*Post, 0),
+		DeletedPosts:    make([]*Post, 0),
+		DeletedFiles:    make([]*Post, 0),
+		StickyChanged:   old.Sticky != new.Sticky,
+		ClosedChanged:   old.Closed != new.Closed,
+		ArchivedChanged: old.Archived != new.Archived,
+	}
+
+	oldPosts := make(map[int]*Post, len(old.Posts))
+	for _, p := range old.Posts {
+		oldPosts[p.No] = p
+	}
+	newPosts := make(map[int]struct{}, len(new.Posts))
+	for _, p := range new.Posts {
+		newPosts[p.No] = struct{}{}
+
+		o, found := oldPosts[p.No]
+		if !found {
+			d.NewPosts = append(d.NewPosts, p)
+		} else if hasFile(o) && !hasFile(p) {
+			d.DeletedFiles = append(d.DeletedFiles, p)
+		}
+	}
+	for _, p := range old.Posts {
+		if _, found := newPosts[p.No]; !found {
+			d.DeletedPosts = append(d.DeletedPosts, p)
+		}
+	}
+
+	return d
+}
+
+// CatalogDiff is the difference between two snapshots of a board's catalog
+type CatalogDiff struct {
+	NewThreads     []*Post // OPs of threads in the new catalog which aren't in the old catalog
+	RemovedThreads []*Post // OPs of threads in the old catalog which aren't in the new catalog
+	BumpedThreads  []*Post // OPs of threads which moved above a thread which was above them
+}
+
+// Empty returns whether nothing changed between the snapshots
+func (d *CatalogDiff) Empty() bool {
+	return len(d.NewThreads) == 0 && len(d.RemovedThreads) == 0 && len(d.BumpedThreads) == 0
+}
+
+// DiffCatalog compares two snapshots of the same board's catalog. A thread is
+// bumped if it's now above a thread which was above it in the old catalog, so
+// threads which only move up because threads above them were removed aren't
+// bumped. Stickies are never bumped. The threads are in the new catalog's order
+// except for removed threads which are in the old catalog's order
+func DiffCatalog(old, new *Catalog) *CatalogDiff {
+	d := &CatalogDiff{
+		NewThreads:     make([]*Post, 0),
+		RemovedThreads: make([]*Post, 0),
+		BumpedThreads:  make([]*Post, 0),
+	}
+
+	oldThreads := catalogThreads(old)
+	newThreads := catalogThreads(new)
+	oldOrder := make(map[int]int, len(oldThreads))
+	for i, op := range oldThreads {
+		oldOrder[op.No] = i
+	}
+	newOrder := make(map[int]int, len(newThreads))
+	for i, op := range newThreads {
+		newOrder[op.No] = i
+	}
+
+	for _, op := range oldThreads {
+		if _, found := newOrder[op.No]; !found {
+			d.RemovedThreads = append(d.RemovedThreads, op)
+		}
+	}
+
+	for _, op := range newThreads {
+		if _, found := oldOrder[op.No]; !found {
+			d.NewThreads = append(d.NewThreads, op)
+		}
+	}
+
+	newNos := make([]int, len(newThreads))
+	for i, op := range newThreads {
+		newNos[i] = op.No
+	}
+	bumped := bumpedThreads(oldOrder, newNos)
+	for _, op := range newThreads {
+		if _, found := bumped[op.No]; found && !bool(op.Sticky) {
+			d.BumpedThreads = append(d.BumpedThreads, op)
+		}
+	}
+
+	return d
+}
+
+// bumpedThreads returns the threads which are now above a thread which was above
+// them, oldOrder is the position of each thread in the old list and newOrder is
+// the threads in the new list. Threads which only move up because threads above
+// them were removed aren't bumped
+func bumpedThreads(oldOrder map[int]int, newOrder []int) map[int]struct{} {
+	bumped := make(map[int]struct{})
+
+	// Walk up the new list keeping track of the highest old position of the
+	// threads below the current thread, if it's above the current thread's old
+	// position then the current thread was bumped above it
+	highest := len(oldOrder)
+	for i := len(newOrder) - 1; i >= 0; i-- {
+		pos, found := oldOrder[newOrder[i]]
+		if !found {
+			continue
+		}
+		if pos > highest {
+			bumped[newOrder[i]] = struct{}{}
+		}
+		if pos < highest {
+			highest = pos
+		}
+	}
+
+	return bumped
+}
+
+// catalogThreads returns the OPs of all threads in the catalog in order
+func catalogThreads(c *Catalog) []*Post {
+	threads := make([]*Post, 0)
+	for _, p := range c.Pages {
+		threads = append(threads, p.Threads...)
+	}
+	return threads
+}
+
+// hasFile returns whether the post has a file which hasn't been deleted
+func hasFile(p *Post) bool {
+	return p.HasFile && !bool(p.FileDeleted)
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func postNos(posts []*Post) []int {
+	nos := make([]int, 0, len(posts))
+	for _, p := range posts {
+		nos = append(nos, p.No)
+	}
+	return nos
+}
+
+func TestDiffThread(t *testing.T) {
+	old := &Thread{No: 1, Posts: []*Post{
+		{No: 1, HasFile: true},
+		{No: 2, HasFile: true},
+		{No: 3},
+		{No: 4, HasFile: true},
+	}}
+	new := &Thread{No: 1, Closed: true, Posts: []*Post{
+		{No: 1, HasFile: true},
+		{No: 2, FileDeleted: true},
+		{No: 4, HasFile: true, FileDeleted: true},
+		{No: 5},
+		{No: 6, HasFile: true},
+	}}
+
+	d := DiffThread(old, new)
+	if !reflect.DeepEqual(postNos(d.NewPosts), []int{5, 6}) {
+		t.Errorf("wrong new posts: %v\n", postNos(d.NewPosts))
+	}
+	if !reflect.DeepEqual(postNos(d.DeletedPosts), []int{3}) {
+		t.Errorf("wrong deleted posts: %v\n", postNos(d.DeletedPosts))
+	}
+	if !reflect.DeepEqual(postNos(d.DeletedFiles), []int{2, 4}) {
+		t.Errorf("wrong deleted files: %v\n", postNos(d.DeletedFiles))
+	}
+	if !d.ClosedChanged || d.StickyChanged || d.ArchivedChanged {
+		t.Errorf("wrong state changes: %+v\n", d)
+	}
+	if d.Empty() || !DiffThread(new, new).Empty() {
+		t.Errorf("diff emptiness is wrong\n")
+	}
+}
+
+func catalog(threads ...*Post) *Catalog {
+	c := &Catalog{}
+	c.Pages = append(c.Pages, struct {
+		Page    int     `json:"page"`
+		Threads []*Post `json:"threads"`
+	}{Page: 1, Threads: threads})
+	return c
+}
+
+func TestDiffCatalog(t *testing.T) {
+	sticky := &Post{No: 1, Sticky: true}
+	old := catalog(sticky, &Post{No: 10}, &Post{No: 11}, &Post{No: 12}, &Post{No: 13}, &Post{No: 14})
+	// 13 is bumped, 20 is new, 11 is removed so 12 moves up without being bumped
+	new := catalog(sticky, &Post{No: 20}, &Post{No: 13}, &Post{No: 10}, &Post{No: 12}, &Post{No: 14})
+
+	d := DiffCatalog(old, new)
+	if !reflect.DeepEqual(postNos(d.NewThreads), []int{20}) {
+		t.Errorf("wrong new threads: %v\n", postNos(d.NewThreads))
+	}
+	if !reflect.DeepEqual(postNos(d.RemovedThreads), []int{11}) {
+		t.Errorf("wrong removed threads: %v\n", postNos(d.RemovedThreads))
+	}
+	if !reflect.DeepEqual(postNos(d.BumpedThreads), []int{13}) {
+		t.Errorf("wrong bumped threads: %v\n", postNos(d.BumpedThreads))
+	}
+
+	// Newly stickied threads aren't bumped
+	stickied := catalog(&Post{No: 14, Sticky: true}, sticky, &Post{No: 10}, &Post{No: 11}, &Post{No: 12}, &Post{No: 13})
+	if d := DiffCatalog(old, stickied); len(d.BumpedThreads) != 0 {
+		t.Errorf("stickied thread bumped: %v\n", postNos(d.BumpedThreads))
+	}
+	if !DiffCatalog(old, old).Empty() {
+		t.Errorf("diff of the same catalog isn't empty\n")
+	}
+}
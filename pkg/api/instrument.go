@@ -0,0 +1,51 @@
+package api
+
+import (
+	"io"
+	"time"
+)
+
+// Request describes a request made by the client
+type Request struct {
+	Method   string        // The HTTP method
+	Domain   string        // The subdomain the request was made to, e.g. ApiDomain
+	Status   int           // The status code of the response, zero if no response was received
+	Waited   time.Duration // How long the request waited on the rate limiter
+	Duration time.Duration // How long the request took excluding the rate limiter
+	Err      error         // The error which occurred if no response was received
+}
+
+// Instrumenter records metrics about the requests made by a Client,
+// it may be called from multiple goroutines at once so implementations
+// should be safe for concurrent use
+type Instrumenter interface {
+	// ObserveRequest is called once a response is received or the request fails
+	ObserveRequest(r Request)
+	// ObserveBytes is called once a response body has been closed with
+	// the number of bytes which were read from it
+	ObserveBytes(domain string, n int64)
+}
+
+// countingBody counts the bytes read from a response body and
+// reports them to the instrumenter once it's closed
+type countingBody struct {
+	io.ReadCloser
+	domain string
+	n      int64
+	closed bool
+	inst   Instrumenter
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	if !b.closed {
+		b.closed = true
+		b.inst.ObserveBytes(b.domain, b.n)
+	}
+	return b.ReadCloser.Close()
+}
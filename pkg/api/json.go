@@ -1,40 +1,87 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 )
 
-// Bool allows 0/1 to also become boolean.
+// Bool allows 0/1 to also become boolean. It also accepts true/false
+// and quoted values so that changes in the api don't break decoding
 type Bool bool
 
 func (bit *Bool) UnmarshalJSON(b []byte) error {
-	var n int
-	err := json.Unmarshal(b, &n)
+	b = bytes.Trim(bytes.TrimSpace(b), `"`)
+	switch string(b) {
+	case "null", "":
+		*bit = false
+		return nil
+	case "true":
+		*bit = true
+		return nil
+	case "false":
+		*bit = false
+		return nil
+	}
+
+	n, err := strconv.ParseFloat(string(b), 64)
 	if err != nil {
-		return err
+		return fmt.Errorf("error decoding bool: %w", err)
 	}
 	*bit = n == 1
 
 	return nil
 }
 
+// MarshalJSON encodes the bool as 0/1 like the api
+func (bit Bool) MarshalJSON() ([]byte, error) {
+	if bit {
+		return []byte("1"), nil
+	}
+	return []byte("0"), nil
+}
+
 // Timestamp is a time.Time which unmarshalls from a UNIX timestamp
 type Timestamp struct {
 	time.Time
 }
 
-// UnmarshalJSON decodes an int64 timestamp into a time.Time object
+// UnmarshalJSON decodes an int64 timestamp into a time.Time object,
+// a timestamp of 0 or null is decoded as the zero time.Time
 func (p *Timestamp) UnmarshalJSON(bytes []byte) error {
-	// Decode the bytes into an int64
-	var raw int64
+	if string(bytes) == "null" {
+		p.Time = time.Time{}
+		return nil
+	}
+
+	// Decode the bytes into an int64, quoted and
+	// floating point timestamps are also accepted
+	var raw json.Number
 	err := json.Unmarshal(bytes, &raw)
 	if err != nil {
 		return fmt.Errorf("error decoding timestamp: %w", err)
 	}
+	f, err := raw.Float64()
+	if err != nil {
+		return fmt.Errorf("error decoding timestamp: %w", err)
+	}
 
 	// Parse the unix timestamp
-	*&p.Time = time.Unix(raw, 0)
+	if f == 0 {
+		p.Time = time.Time{}
+	} else {
+		p.Time = time.Unix(int64(f), 0)
+	}
 	return nil
 }
+
+// MarshalJSON encodes the time as a UNIX timestamp like
+// the api, the zero time.Time is encoded as 0
+func (p Timestamp) MarshalJSON() ([]byte, error) {
+	if p.IsZero() {
+		return []byte("0"), nil
+	}
+	return []byte(strconv.FormatInt(p.Unix(), 10)), nil
+}
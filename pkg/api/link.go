@@ -0,0 +1,185 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidLink = fmt.Errorf("invalid 4chan link")
+
+// Link is a location on 4chan, i.e. a board, a thread or a post
+type Link struct {
+	Board  string // The board linked to
+	Thread int    // The thread linked to, zero if the link is to a board or if it's unknown
+	Post   int    // The post linked to, zero if the link isn't to a specific post
+	Slug   string // The thread's semantic url, e.g. "welcome-to-po"
+}
+
+// ParseLink parses any form of link to 4chan, this includes:
+//   - Thread URLs, e.g. "https://boards.4channel.org/po/thread/570368/slug#p570400",
+//     the scheme and domain are optional so "po/thread/570368" is also valid
+//   - Board URLs, e.g. "https://boards.4chan.org/po/catalog" or "/po/archive"
+//   - API URLs, e.g. "https://a.4cdn.org/po/thread/570368.json"
+//   - Archive URLs which use the same layout, e.g. "https://archived.moe/po/thread/570368/#570400",
+//     or which link to posts, e.g. "https://archived.moe/po/post/570400/"
+//   - Crosslinks and quotelinks, e.g. ">>>/po/", ">>>/po/570368" or ">>570400"
+//   - Anchors, e.g. "#p570400"
+//
+// The thread of crosslinks and quotelinks is unknown so only their Post is set
+func ParseLink(s string) (Link, error) {
+	s = strings.TrimSpace(s)
+
+	// Crosslinks and quotelinks
+	if strings.HasPrefix(s, ">>>") {
+		parts := strings.SplitN(strings.Trim(strings.TrimPrefix(s, ">>>"), "/"), "/", 2)
+		l := Link{Board: strings.ToLower(parts[0])}
+		if !validBoard(l.Board) {
+			return Link{}, fmt.Errorf("%w: invalid board in crosslink: %s", ErrInvalidLink, s)
+		}
+		if len(parts) == 2 {
+			no, err := strconv.Atoi(parts[1])
+			if err != nil {
+				return Link{}, fmt.Errorf("%w: crosslink isn't to a board or post: %s", ErrInvalidLink, s)
+			}
+			l.Post = no
+		}
+		return l, nil
+	} else if strings.HasPrefix(s, ">>") {
+		no, err := strconv.Atoi(strings.TrimPrefix(s, ">>"))
+		if err != nil {
+			return Link{}, fmt.Errorf("%w: invalid quotelink: %s", ErrInvalidLink, s)
+		}
+		return Link{Post: no}, nil
+	}
+
+	// Links without a scheme which start with a domain, e.g.
+	// "boards.4chan.org/po/", are otherwise parsed as paths
+	if !strings.Contains(s, "://") && !strings.HasPrefix(s, "/") {
+		if first := strings.SplitN(s, "/", 2)[0]; strings.Contains(first, ".") {
+			s = "//" + s
+		}
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return Link{}, fmt.Errorf("%w: %s", ErrInvalidLink, err)
+	}
+
+	var l Link
+	l.Post = parseAnchor(u.Fragment)
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if parts[0] == "" {
+		if l.Post == 0 {
+			return Link{}, fmt.Errorf("%w: no board or post in link: %s", ErrInvalidLink, s)
+		}
+		return l, nil
+	}
+	l.Board = strings.ToLower(parts[0])
+	if !validBoard(l.Board) {
+		return Link{}, fmt.Errorf("%w: invalid board in link: %s", ErrInvalidLink, s)
+	}
+
+	// API and old thread links end with an extension, e.g. "570368.json"
+	last := len(parts) - 1
+	parts[last] = strings.TrimSuffix(strings.TrimSuffix(parts[last], ".json"), ".html")
+
+	if len(parts) == 1 {
+		return l, nil
+	}
+	switch parts[1] {
+	case "thread", "res", "post":
+		if len(parts) < 3 {
+			return Link{}, fmt.Errorf("%w: no id in link: %s", ErrInvalidLink, s)
+		}
+		no, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return Link{}, fmt.Errorf("%w: could not parse id as int: %s", ErrInvalidLink, parts[2])
+		}
+		if parts[1] == "post" {
+			l.Post = no
+			return l, nil
+		}
+		l.Thread = no
+		if len(parts) >= 4 {
+			l.Slug = parts[3]
+		}
+	case "catalog", "archive":
+		// Links to the board's catalog or archive
+	default:
+		// Links to pages of the board, e.g. "/po/2"
+		if _, err := strconv.Atoi(parts[1]); err != nil {
+			return Link{}, fmt.Errorf("%w: unknown link: %s", ErrInvalidLink, s)
+		}
+	}
+
+	return l, nil
+}
+
+// URL formats the link as a URL on the boards domain. If the
+// Thread is unknown then the link is assumed to be to an OP
+// so the Post is used as the thread
+func (l Link) URL(ssl bool) string {
+	thread := l.Thread
+	if thread == 0 {
+		thread = l.Post
+	}
+	if thread == 0 {
+		return formatURL(ssl, BoardsDomain, l.Board, "")
+	}
+
+	endpoint := fmt.Sprintf("thread/%d", thread)
+	if l.Slug != "" {
+		endpoint += "/" + l.Slug
+	}
+	if l.Post != 0 {
+		endpoint += fmt.Sprintf("#p%d", l.Post)
+	}
+	return formatURL(ssl, BoardsDomain, l.Board, endpoint)
+}
+
+// String formats the link as a crosslink, e.g. ">>>/po/570400",
+// or as a quotelink if it has no board, e.g. ">>570400"
+func (l Link) String() string {
+	no := l.Post
+	if no == 0 {
+		no = l.Thread
+	}
+
+	switch {
+	case l.Board == "":
+		return fmt.Sprintf(">>%d", no)
+	case no == 0:
+		return fmt.Sprintf(">>>/%s/", l.Board)
+	default:
+		return fmt.Sprintf(">>>/%s/%d", l.Board, no)
+	}
+}
+
+// parseAnchor parses the post ID from anchors such as "p570400",
+// "q570400" or "570400", zero is returned for other anchors
+func parseAnchor(fragment string) int {
+	if strings.HasPrefix(fragment, "p") || strings.HasPrefix(fragment, "q") {
+		fragment = fragment[1:]
+	}
+	no, err := strconv.Atoi(fragment)
+	if err != nil {
+		return 0
+	}
+	return no
+}
+
+// validBoard returns whether the name could be a board, i.e. it's
+// non-empty and made up of only lowercase letters and digits
+func validBoard(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseLink(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Link
+		err  bool
+	}{
+		{"https://boards.4channel.org/po/thread/570368", Link{Board: "po", Thread: 570368}, false},
+		{"https://boards.4channel.org/po/thread/570368/welcome-to-po", Link{Board: "po", Thread: 570368, Slug: "welcome-to-po"}, false},
+		{"https://boards.4chan.org/po/thread/570368/welcome-to-po#p570400", Link{Board: "po", Thread: 570368, Post: 570400, Slug: "welcome-to-po"}, false},
+		{"http://boards.4chan.org/po/thread/570368#q570400", Link{Board: "po", Thread: 570368, Post: 570400}, false},
+		{"//boards.4chan.org/po/thread/570368", Link{Board: "po", Thread: 570368}, false},
+		{"boards.4channel.org/po/thread/570368", Link{Board: "po", Thread: 570368}, false},
+		{"po/thread/570368", Link{Board: "po", Thread: 570368}, false},
+		{"/po/thread/570368#p570400", Link{Board: "po", Thread: 570368, Post: 570400}, false},
+		{"https://boards.4chan.org/po/res/570368.html", Link{Board: "po", Thread: 570368}, false},
+		{"https://a.4cdn.org/po/thread/570368.json", Link{Board: "po", Thread: 570368}, false},
+		{"https://archived.moe/po/thread/570368/#570400", Link{Board: "po", Thread: 570368, Post: 570400}, false},
+		{"https://archived.moe/po/post/570400/", Link{Board: "po", Post: 570400}, false},
+		{"https://boards.4chan.org/po/", Link{Board: "po"}, false},
+		{"https://boards.4chan.org/po/catalog", Link{Board: "po"}, false},
+		{"https://boards.4chan.org/po/archive", Link{Board: "po"}, false},
+		{"https://boards.4chan.org/po/2", Link{Board: "po"}, false},
+		{">>>/po/", Link{Board: "po"}, false},
+		{">>>/po/570368", Link{Board: "po", Post: 570368}, false},
+		{">>570400", Link{Post: 570400}, false},
+		{"#p570400", Link{Post: 570400}, false},
+		{">>>/po/search", Link{}, true},
+		{">>abc", Link{}, true},
+		{"https://boards.4chan.org/", Link{}, true},
+		{"https://boards.4chan.org/po/thread/abc", Link{}, true},
+		{"https://boards.4chan.org/po/thread", Link{}, true},
+		{"https://boards.4chan.org/po/unknown", Link{}, true},
+		{"https://boards.4chan.org/P_O/thread/1", Link{}, true},
+	}
+
+	for _, tc := range tests {
+		l, err := ParseLink(tc.in)
+		if (err != nil) != tc.err {
+			t.Errorf("link %q returned unexpected error: %v\n", tc.in, err)
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrInvalidLink) {
+			t.Errorf("link %q error doesn't wrap ErrInvalidLink: %s\n", tc.in, err)
+		}
+		if l != tc.want {
+			t.Errorf("link %q parsed as %+v but expected %+v\n", tc.in, l, tc.want)
+		}
+	}
+}
+
+func TestFormatLink(t *testing.T) {
+	tests := []struct {
+		l         Link
+		url, text string
+	}{
+		{Link{Board: "po"}, "https://boards.4chan.org/po/", ">>>/po/"},
+		{Link{Board: "po", Thread: 570368}, "https://boards.4chan.org/po/thread/570368", ">>>/po/570368"},
+		{Link{Board: "po", Thread: 570368, Slug: "welcome-to-po"}, "https://boards.4chan.org/po/thread/570368/welcome-to-po", ">>>/po/570368"},
+		{Link{Board: "po", Thread: 570368, Post: 570400}, "https://boards.4chan.org/po/thread/570368#p570400", ">>>/po/570400"},
+		{Link{Board: "po", Post: 570368}, "https://boards.4chan.org/po/thread/570368#p570368", ">>>/po/570368"},
+	}
+
+	for _, tc := range tests {
+		if u := tc.l.URL(true); u != tc.url {
+			t.Errorf("link %+v formatted as url %s but expected %s\n", tc.l, u, tc.url)
+		}
+		if s := tc.l.String(); s != tc.text {
+			t.Errorf("link %+v formatted as %s but expected %s\n", tc.l, s, tc.text)
+		}
+
+		// Formatted links should parse back into themselves
+		l, err := ParseLink(tc.l.URL(true))
+		if err != nil || l.Board != tc.l.Board || l.Slug != tc.l.Slug {
+			t.Errorf("link %+v didn't round trip, got %+v, err: %v\n", tc.l, l, err)
+		}
+	}
+}
@@ -2,44 +2,65 @@ package api
 
 import (
 	"encoding/json"
+	"reflect"
+	"strings"
 	"time"
 )
 
 // modified time
 
-type modTime time.Time
+// modTime keeps track of when an object was fetched so it can be refreshed
+type modTime struct {
+	// ModifiedSince is when the object was fetched, it's sent in the
+	// If-Modified-Since header when the object is refreshed. It's kept
+	// when the object is encoded as JSON so saved objects can be refreshed
+	ModifiedSince Timestamp `json:"modified_since"`
+}
+
+func newModTime(t time.Time) modTime {
+	return modTime{ModifiedSince: Timestamp{Time: t}}
+}
 
 func (m *modTime) time() time.Time {
-	return time.Time(*m)
+	return m.ModifiedSince.Time
+}
+
+func (m *modTime) mod() *modTime {
+	return m
 }
 
 func (m *modTime) ClearLastModified() {
-	*m = modTime(time.Time{})
+	m.ModifiedSince = Timestamp{}
 }
 
 // general structs
 
 type Post struct {
 	// Custom fields implemented by crow
-	Board   string `json:"board"`    // The directory the board is located in.
-	HasFile bool   `json:"has_file"` // Whether the post has a file attached
+	Board   string                     `json:"board"`    // The directory the board is located in.
+	HasFile bool                       `json:"has_file"` // Whether the post has a file attached
+	Extra   map[string]json.RawMessage `json:"-"`        // Fields returned by the API which crow doesn't know about, e.g. xa fields
 
 	// Fields from the API
 	No              int         `json:"no"`             // The numeric post ID
 	RepliesTo       int         `json:"resto"`          // For replies: this is the ID of the thread being replied to. For OP: this value is zero
 	Sticky          Bool        `json:"sticky"`         // If the thread is being pinned to the top of the page
+	StickyCap       int         `json:"sticky_cap"`     // For rolling stickies, the maximum number of replies kept in the thread
 	Closed          Bool        `json:"closed"`         // If the thread is closed to replies
 	Now             string      `json:"now"`            // MM/DD/YY(Day)HH:MM (:SS on some boards), EST/EDT timezone
 	Time            Timestamp   `json:"time"`           // UNIX timestamp the post was created
 	Name            string      `json:"name"`           // Name user posted with. Defaults to Anonymous
 	Trip            string      `json:"trip"`           // The user's tripcode, in format: !tripcode or !!securetripcode
 	ID              string      `json:"id"`             // The poster's ID
-	CapCode         string      `json:"cap_code"`       // The capcode identifier for a post
+	Capcode         Capcode     `json:"capcode"`        // The capcode identifier for a post
 	Country         string      `json:"country"`        // Poster's ISO 3166-1 alpha-2 country code
 	CountryName     string      `json:"country_name"`   // Poster's country name
+	TrollCountry    string      `json:"troll_country"`  // Poster's troll flag code, only on boards with troll flags
+	BoardFlag       string      `json:"board_flag"`     // Poster's board flag code, only on boards with board specific flags
+	FlagName        string      `json:"flag_name"`      // Poster's board flag name
 	Subject         string      `json:"sub"`            // OP Subject text
 	Comment         string      `json:"com"`            // Comment (HTML escaped)
-	ImageID         json.Number `json:"tim"`            // Unix timestamp + microtime that an image was uploaded
+	ImageID         json.Number `json:"tim,omitempty"`  // Unix timestamp + microtime that an image was uploaded
 	Filename        string      `json:"filename"`       // Filename as it appeared on the poster's device
 	Ext             string      `json:"ext"`            // Filetype
 	Filesize        int         `json:"fsize"`          // Size of uploaded file in bytes
@@ -55,17 +76,97 @@ type Post struct {
 	OmittedImages   int         `json:"omitted_images"` // Number of image replies minus the number of previewed image replies
 	Replies         int         `json:"replies"`        // Total number of replies to a thread
 	Images          int         `json:"images"`         // Total number of image replies to a thread
-	BumpLimit       Bool        `json:"bump_limit"`     // If a thread has reached bumplimit, it will no longer bump
-	ImageLimit      Bool        `json:"image_limit"`    // If an image has reached image limit, no more image replies can be made
+	BumpLimit       Bool        `json:"bumplimit"`      // If a thread has reached bumplimit, it will no longer bump
+	ImageLimit      Bool        `json:"imagelimit"`     // If an image has reached image limit, no more image replies can be made
 	LastModified    Timestamp   `json:"last_modified"`  // The UNIX timestamp marking the last time the thread was modified (post added/modified/deleted, thread closed/sticky settings modified)
 	Tag             string      `json:"tag"`            // The category of .swf upload
 	SemanticURL     string      `json:"semantic_url"`   // SEO URL slug for thread
 	Since4Pass      int         `json:"since4pass"`     // Year 4chan pass bought
-	UniqueIPs       int         `json:"unique_ips"`     // Number of unique posters in a thread
+	UniqueIPs       int         `json:"unique_ips"`     // Number of unique posters in a thread, only set on the OP
 	MImg            Bool        `json:"m_img"`          // Mobile optimized image exists for post
 	LastReplies     []Post      `json:"last_replies"`   // JSON representation of the most recent replies to a thread
 	Archived        Bool        `json:"archived"`       // Thread has reached the board's archive
-	ArchivedOn      Timestamp   `json:"archived_on"`    // UNIX timestamp the post was archived
+	ArchivedOn      Timestamp   `json:"archived_on"`    // UNIX timestamp the post was archived, zero if the thread isn't archived
+}
+
+// postFields are the JSON keys of the fields in a Post
+var postFields = func() map[string]struct{} {
+	fields := make(map[string]struct{})
+	t := reflect.TypeOf(Post{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = struct{}{}
+		}
+	}
+	return fields
+}()
+
+// UnmarshalJSON decodes the post, any fields which aren't part
+// of the Post struct are kept in Extra
+func (p *Post) UnmarshalJSON(b []byte) error {
+	type post Post
+	err := json.Unmarshal(b, (*post)(p))
+	if err != nil {
+		return err
+	}
+
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal(b, &raw)
+	if err != nil {
+		return err
+	}
+	for k := range raw {
+		if _, found := postFields[k]; found {
+			delete(raw, k)
+		}
+	}
+	if len(raw) > 0 {
+		p.Extra = raw
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the post, the fields in Extra are
+// included so the post is encoded as the api returned it
+func (p Post) MarshalJSON() ([]byte, error) {
+	type post Post
+	b, err := json.Marshal(post(p))
+	if err != nil || len(p.Extra) == 0 {
+		return b, err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range p.Extra {
+		if _, found := fields[k]; !found {
+			fields[k] = v
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// Capcode identifies the staff position of a poster
+type Capcode string
+
+const (
+	CapcodeNone           Capcode = ""                // The poster isn't staff
+	CapcodeMod            Capcode = "mod"             // Moderator
+	CapcodeAdmin          Capcode = "admin"           // Administrator
+	CapcodeAdminHighlight Capcode = "admin_highlight" // Administrator with a highlighted post
+	CapcodeManager        Capcode = "manager"         // Manager
+	CapcodeDeveloper      Capcode = "developer"       // Developer
+	CapcodeFounder        Capcode = "founder"         // Founder
+	CapcodeVerified       Capcode = "verified"        // Verified user
+)
+
+// IsStaff returns whether the capcode belongs to a member of staff
+func (c Capcode) IsStaff() bool {
+	return c != CapcodeNone && c != CapcodeVerified
 }
 
 // boards.json
@@ -94,23 +195,76 @@ type Board struct {
 		Replies int `json:"replies"` // Reply cooldown time
 		Images  int `json:"images"`  // Image cooldown time
 	} `json:"cooldowns"`
-	MetaDescription string `json:"meta_description"` // SEO meta description content for a board
-	Spoilers        Bool   `json:"spoilers"`         // Are spoilers enabled
-	CustomSpoilers  int    `json:"custom_spoilers"`  // How many custom spoilers does the board have
-	IsArchived      Bool   `json:"is_archived"`      // Are archives enabled for the board
-	TrollFlags      Bool   `json:"troll_flags"`      // Are troll flags enabled on the board
-	CountryFlags    Bool   `json:"country_flags"`    // Are flags showing the poster's country enabled on the board
-	UserIDs         Bool   `json:"user_ids"`         // Are poster ID tags enabled on the board
-	Oekaki          Bool   `json:"oekaki"`           // Can users submit drawings via browser the Oekaki app
-	SjisTags        Bool   `json:"sjis_tags"`        // Can users submit sjis drawings using the [sjis] tags
-	CodeTags        Bool   `json:"code_tags"`        // Board supports code syntax highlighting using the [code] tags
-	MathTags        Bool   `json:"math_tags"`        // Board supports [math] TeX and [eqn] tags
-	TextOnly        Bool   `json:"text_only"`        // Is image posting disabled for the board
-	ForcedAnon      Bool   `json:"forced_anon"`      // Is the name field disabled on the board
-	WebmAudio       Bool   `json:"webm_audio"`       // Are webms with audio allowed?
-	RequireSubject  Bool   `json:"require_subject"`  // Do OPs require a subject
-	MinImageWidth   int    `json:"min_image_width"`  // What is the minimum image width (in pixels)
-	MinImageHeight  int    `json:"min_image_height"` // What is the minimum image height (in pixels)
+	MetaDescription string            `json:"meta_description"` // SEO meta description content for a board
+	Spoilers        Bool              `json:"spoilers"`         // Are spoilers enabled
+	CustomSpoilers  int               `json:"custom_spoilers"`  // How many custom spoilers does the board have
+	IsArchived      Bool              `json:"is_archived"`      // Are archives enabled for the board
+	TrollFlags      Bool              `json:"troll_flags"`      // Are troll flags enabled on the board
+	CountryFlags    Bool              `json:"country_flags"`    // Are flags showing the poster's country enabled on the board
+	BoardFlags      map[string]string `json:"board_flags"`      // The board specific flags which posters can choose, keyed by their code
+	UserIDs         Bool              `json:"user_ids"`         // Are poster ID tags enabled on the board
+	Oekaki          Bool              `json:"oekaki"`           // Can users submit drawings via browser the Oekaki app
+	SjisTags        Bool              `json:"sjis_tags"`        // Can users submit sjis drawings using the [sjis] tags
+	CodeTags        Bool              `json:"code_tags"`        // Board supports code syntax highlighting using the [code] tags
+	MathTags        Bool              `json:"math_tags"`        // Board supports [math] TeX and [eqn] tags
+	TextOnly        Bool              `json:"text_only"`        // Is image posting disabled for the board
+	ForcedAnon      Bool              `json:"forced_anon"`      // Is the name field disabled on the board
+	WebmAudio       Bool              `json:"webm_audio"`       // Are webms with audio allowed?
+	RequireSubject  Bool              `json:"require_subject"`  // Do OPs require a subject
+	MinImageWidth   int               `json:"min_image_width"`  // What is the minimum image width (in pixels)
+	MinImageHeight  int               `json:"min_image_height"` // What is the minimum image height (in pixels)
+}
+
+// HasFlags returns whether posts on the board can have country, troll or board flags
+func (b *Board) HasFlags() bool {
+	return bool(b.CountryFlags) || bool(b.TrollFlags) || len(b.BoardFlags) > 0
+}
+
+// FileSizeLimit returns the maximum size in bytes of a file with the extension
+func (b *Board) FileSizeLimit(ext string) int64 {
+	if strings.EqualFold(ext, ".webm") {
+		return int64(b.MaxWebmFilesize) * 1024
+	}
+	return int64(b.MaxFilesize) * 1024
+}
+
+// PosterID returns the poster's ID and whether the board has IDs
+func (p *Post) PosterID(b *Board) (string, bool) {
+	if !bool(b.UserIDs) {
+		return "", false
+	}
+	return p.ID, true
+}
+
+// Flag returns the name of the poster's flag and whether the board has flags,
+// board flags are preferred over country flags as with Post.FlagURL()
+func (p *Post) Flag(b *Board) (string, bool) {
+	if !b.HasFlags() {
+		return "", false
+	}
+	if p.BoardFlag != "" {
+		if name, found := b.BoardFlags[p.BoardFlag]; found {
+			return name, true
+		}
+		return p.FlagName, true
+	}
+	return p.CountryName, true
+}
+
+// BumpsLeft returns how many more replies the thread can receive before it stops bumping
+func (t *Thread) BumpsLeft(b *Board) int {
+	if left := b.BumpLimit - t.Replies; left > 0 {
+		return left
+	}
+	return 0
+}
+
+// ImagesLeft returns how many more image replies the thread can receive
+func (t *Thread) ImagesLeft(b *Board) int {
+	if left := b.ImageLimit - t.Images; left > 0 {
+		return left
+	}
+	return 0
 }
 
 // threads.json
@@ -118,15 +272,19 @@ type Board struct {
 type ThreadList struct {
 	modTime
 
-	Board string `json:"board"`
-	Pages []struct {
-		Page    int `json:"page"` // The page number that the following Threads slice is on
-		Threads []struct {
-			No           int       `json:"no"`            // The OP ID of a thread
-			LastModified Timestamp `json:"last_modified"` // The UNIX timestamp marking the last time the thread was modified (post added/modified/deleted, thread closed/sticky settings modified)
-			Replies      int       `json:"replies"`       // A numeric count of the number of replies in the thread
-		} `json:"threads"` // The threads on the page
-	} `json:"pages"`
+	Board string           `json:"board"`
+	Pages []ThreadListPage `json:"pages"`
+}
+
+type ThreadListPage struct {
+	Page    int             `json:"page"`    // The page number that the following Threads slice is on
+	Threads []ThreadSummary `json:"threads"` // The threads on the page
+}
+
+type ThreadSummary struct {
+	No           int       `json:"no"`            // The OP ID of a thread
+	LastModified Timestamp `json:"last_modified"` // The UNIX timestamp marking the last time the thread was modified (post added/modified/deleted, thread closed/sticky settings modified)
+	Replies      int       `json:"replies"`       // A numeric count of the number of replies in the thread
 }
 
 // catalog.json
@@ -169,10 +327,11 @@ type Thread struct {
 	Subject      string    `json:"sub"`           // OP Subject text
 	Comment      string    `json:"com"`           // Comment (HTML escaped)
 	Replies      int       `json:"replies"`       // Total number of replies to a thread
+	Images       int       `json:"images"`        // Total number of image replies to a thread
 	LastModified Timestamp `json:"last_modified"` // The UNIX timestamp marking the last time the thread was modified (post added/modified/deleted, thread closed/sticky settings modified)
 	UniqueIPs    int       `json:"unique_ips"`    // Number of unique posters in a thread
-	BumpLimit    Bool      `json:"bump_limit"`    // If a thread has reached bumplimit, it will no longer bump
-	ImageLimit   Bool      `json:"image_limit"`   // If an image has reached image limit, no more image replies can be made
+	BumpLimit    Bool      `json:"bumplimit"`     // If a thread has reached bumplimit, it will no longer bump
+	ImageLimit   Bool      `json:"imagelimit"`    // If an image has reached image limit, no more image replies can be made
 	Archived     Bool      `json:"archived"`      // Thread has reached the board's archive
 	ArchivedOn   Timestamp `json:"archived_on"`   // UNIX timestamp the post was archived
 	Sticky       Bool      `json:"sticky"`        // If the thread is being pinned to the top of the page
@@ -186,6 +345,7 @@ func (t *Thread) addThreadAttributes(mt modTime) {
 	t.Subject = t.Posts[0].Subject
 	t.Comment = t.Posts[0].Comment
 	t.Replies = t.Posts[0].Replies
+	t.Images = t.Posts[0].Images
 	t.LastModified = t.Posts[0].LastModified
 	t.UniqueIPs = t.Posts[0].UniqueIPs
 	t.BumpLimit = t.Posts[0].BumpLimit
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPostFields(t *testing.T) {
+	tests := []struct {
+		field   string
+		fixture string
+		check   func(p *Post) bool
+	}{
+		{"board_flag", `{"no": 1, "board_flag": "AN"}`, func(p *Post) bool { return p.BoardFlag == "AN" }},
+		{"flag_name", `{"no": 1, "flag_name": "Anarchist"}`, func(p *Post) bool { return p.FlagName == "Anarchist" }},
+		{"capcode", `{"no": 1, "capcode": "admin"}`, func(p *Post) bool { return p.Capcode == CapcodeAdmin && p.Capcode.IsStaff() }},
+		{"capcode verified", `{"no": 1, "capcode": "verified"}`, func(p *Post) bool { return p.Capcode == CapcodeVerified && !p.Capcode.IsStaff() }},
+		{"capcode missing", `{"no": 1}`, func(p *Post) bool { return p.Capcode == CapcodeNone && !p.Capcode.IsStaff() }},
+		{"unique_ips", `{"no": 1, "unique_ips": 42}`, func(p *Post) bool { return p.UniqueIPs == 42 }},
+		{"archived_on", `{"no": 1, "archived": 1, "archived_on": 1600000000}`, func(p *Post) bool {
+			return bool(p.Archived) && p.ArchivedOn.Equal(time.Unix(1600000000, 0))
+		}},
+		{"archived_on missing", `{"no": 1}`, func(p *Post) bool { return p.ArchivedOn.IsZero() }},
+		{"archived_on zero", `{"no": 1, "archived_on": 0}`, func(p *Post) bool { return p.ArchivedOn.IsZero() }},
+		{"m_img", `{"no": 1, "m_img": 1}`, func(p *Post) bool { return bool(p.MImg) }},
+		{"sticky_cap", `{"no": 1, "sticky": 1, "sticky_cap": 300}`, func(p *Post) bool { return bool(p.Sticky) && p.StickyCap == 300 }},
+		{"bumplimit", `{"no": 1, "bumplimit": 1}`, func(p *Post) bool { return bool(p.BumpLimit) }},
+		{"imagelimit", `{"no": 1, "imagelimit": 1}`, func(p *Post) bool { return bool(p.ImageLimit) }},
+		{"xa fields", `{"no": 1, "xa21s": "flag", "xa21l": 3}`, func(p *Post) bool {
+			return len(p.Extra) == 2 && string(p.Extra["xa21s"]) == `"flag"` && string(p.Extra["xa21l"]) == "3"
+		}},
+		{"known fields aren't extra", `{"no": 1, "com": "hi", "tim": 123}`, func(p *Post) bool { return p.Extra == nil }},
+		{"bool as true/false", `{"no": 1, "closed": true, "sticky": false}`, func(p *Post) bool { return bool(p.Closed) && !bool(p.Sticky) }},
+		{"bool as string", `{"no": 1, "closed": "1"}`, func(p *Post) bool { return bool(p.Closed) }},
+		{"timestamp as string", `{"no": 1, "time": "1600000000"}`, func(p *Post) bool { return p.Time.Equal(time.Unix(1600000000, 0)) }},
+		{"timestamp as null", `{"no": 1, "time": null}`, func(p *Post) bool { return p.Time.IsZero() }},
+		{"last_replies", `{"no": 1, "last_replies": [{"no": 2, "capcode": "mod", "xa": 1}]}`, func(p *Post) bool {
+			return len(p.LastReplies) == 1 && p.LastReplies[0].Capcode == CapcodeMod && p.LastReplies[0].Extra != nil
+		}},
+	}
+
+	for _, tc := range tests {
+		var p Post
+		err := json.Unmarshal([]byte(tc.fixture), &p)
+		if err != nil {
+			t.Errorf("%s: failed to decode fixture: %s\n", tc.field, err)
+			continue
+		}
+		if !tc.check(&p) {
+			t.Errorf("%s: decoded incorrectly: %+v\n", tc.field, p)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	fixture := `{"posts": [
+		{"no": 570368, "sticky": 1, "closed": 0, "time": 1546293948, "com": "hi", "tim": 1546293948883, "ext": ".png", "fsize": 10,
+		 "capcode": "mod", "archived_on": 0, "semantic_url": "welcome", "xa21s": "extra"},
+		{"no": 570400, "resto": 570368, "time": 1546293950, "m_img": 1}
+	]}`
+
+	var th Thread
+	err := json.Unmarshal([]byte(fixture), &th)
+	if err != nil {
+		t.Errorf("failed to decode fixture: %s\n", err)
+		return
+	}
+	th.addThreadAttributes(newModTime(time.Unix(1600000000, 0)))
+	th.Board = "po"
+
+	b, err := json.Marshal(&th)
+	if err != nil {
+		t.Errorf("failed to encode thread: %s\n", err)
+		return
+	}
+
+	// The api's wire format is used
+	var raw struct {
+		ModifiedSince int64                        `json:"modified_since"`
+		Sticky        int                          `json:"sticky"`
+		Posts         []map[string]json.RawMessage `json:"posts"`
+	}
+	err = json.Unmarshal(b, &raw)
+	if err != nil {
+		t.Errorf("failed to decode encoded thread: %s\n", err)
+		return
+	}
+	if raw.ModifiedSince != 1600000000 || raw.Sticky != 1 {
+		t.Errorf("thread encoded in the wrong format: %s\n", b)
+	}
+	if string(raw.Posts[0]["time"]) != "1546293948" || string(raw.Posts[0]["closed"]) != "0" ||
+		string(raw.Posts[0]["archived_on"]) != "0" || string(raw.Posts[0]["xa21s"]) != `"extra"` {
+		t.Errorf("post encoded in the wrong format: %s\n", b)
+	}
+
+	// Decoding the encoded thread gives the same thread
+	var decoded Thread
+	err = json.Unmarshal(b, &decoded)
+	if err != nil {
+		t.Errorf("failed to decode encoded thread: %s\n", err)
+		return
+	}
+	if !reflect.DeepEqual(th, decoded) {
+		t.Errorf("thread changed after round trip\nbefore: %+v\nafter:  %+v\n", th, decoded)
+	}
+	if !decoded.time().Equal(time.Unix(1600000000, 0)) {
+		t.Errorf("modified since time not restored: %s\n", decoded.time())
+	}
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// BoardEventType is the type of change to a thread in a board's threads.json
+type BoardEventType int
+
+const (
+	ThreadAdded    BoardEventType = iota // A thread was created or appeared on the board
+	ThreadModified                       // The thread's last modified time advanced, e.g. a post was added or deleted
+	ThreadBumped                         // The thread moved above a thread which was above it
+	ThreadMoved                          // The thread is on a different page
+	ThreadPruned                         // The thread fell off the board, e.g. it was archived, pruned or deleted
+)
+
+func (t BoardEventType) String() string {
+	switch t {
+	case ThreadAdded:
+		return "thread_added"
+	case ThreadModified:
+		return "thread_modified"
+	case ThreadBumped:
+		return "thread_bumped"
+	case ThreadMoved:
+		return "thread_moved"
+	case ThreadPruned:
+		return "thread_pruned"
+	default:
+		return "unknown"
+	}
+}
+
+// BoardEvent is a change to a thread in a board's threads.json
+type BoardEvent struct {
+	Type         BoardEventType
+	Board        string    // The board of the thread
+	No           int       // The OP ID of the thread
+	Page         int       // The page the thread is on, zero if it was pruned
+	OldPage      int       // The page the thread was on, zero if it was added
+	LastModified time.Time // When the thread was last modified
+	Replies      int       // The number of replies in the thread
+}
+
+// DiffThreadList compares two snapshots of the same board's threads.json and returns
+// the events which describe the changes, a thread may have multiple events, e.g. it's
+// modified, bumped and moved pages. Events for pruned threads come first followed by
+// the events of the other threads in the new list's order
+func DiffThreadList(old, new *ThreadList) []BoardEvent {
+	events := make([]BoardEvent, 0)
+
+	oldThreads, oldOrder := threadListThreads(old)
+	newThreads, _ := threadListThreads(new)
+	listed := make(map[int]struct{}, len(newThreads))
+	newNos := make([]int, len(newThreads))
+	for i, t := range newThreads {
+		listed[t.No] = struct{}{}
+		newNos[i] = t.No
+	}
+
+	for _, t := range oldThreads {
+		if _, found := listed[t.No]; !found {
+			events = append(events, t.event(ThreadPruned, new.Board, 0, t.page))
+		}
+	}
+
+	bumped := bumpedThreads(oldOrder, newNos)
+	for _, t := range newThreads {
+		pos, found := oldOrder[t.No]
+		if !found {
+			events = append(events, t.event(ThreadAdded, new.Board, t.page, 0))
+			continue
+		}
+
+		o := oldThreads[pos]
+		if t.LastModified.After(o.LastModified.Time) {
+			events = append(events, t.event(ThreadModified, new.Board, t.page, o.page))
+		}
+		if _, found := bumped[t.No]; found {
+			events = append(events, t.event(ThreadBumped, new.Board, t.page, o.page))
+		}
+		if t.page != o.page {
+			events = append(events, t.event(ThreadMoved, new.Board, t.page, o.page))
+		}
+	}
+
+	return events
+}
+
+// BoardMonitor polls a board's threads.json to detect when its threads change, this
+// is the cheapest way to find out which threads need to be refreshed. Threads and
+// Thread aren't safe to call from other goroutines while Run is running, they can
+// be called from the function given to Run
+type BoardMonitor struct {
+	c      *Client
+	board  string
+	poller *Poller[*ThreadList]
+}
+
+// NewBoardMonitor fetches the board's threads.json and creates a monitor which
+// refreshes it every interval
+func (c *Client) NewBoardMonitor(board string, interval time.Duration) (*BoardMonitor, error) {
+	tl, _, err := c.GetThreads(board)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoardMonitor{
+		c:      c,
+		board:  tl.Board,
+		poller: NewPoller(tl, interval, c.RefreshThreads),
+	}, nil
+}
+
+// Threads returns the newest version of the board's threads.json
+func (m *BoardMonitor) Threads() *ThreadList {
+	return m.poller.Current()
+}
+
+// Thread returns the thread's summary from the newest
+// threads.json and whether it's on the board
+func (m *BoardMonitor) Thread(no int) (ThreadSummary, bool) {
+	for _, p := range m.Threads().Pages {
+		for _, t := range p.Threads {
+			if t.No == no {
+				return t, true
+			}
+		}
+	}
+	return ThreadSummary{}, false
+}
+
+// Run refreshes threads.json every interval and calls fn with the events each
+// time it changes until the context is done, the context's error is returned.
+// Errors refreshing threads.json are logged and the monitor keeps polling
+func (m *BoardMonitor) Run(ctx context.Context, fn func(events []BoardEvent)) error {
+	old := m.Threads()
+	return m.poller.Poll(ctx, func(u Update[*ThreadList]) error {
+		if u.Err != nil {
+			m.c.logger().Warn().Err(u.Err).Str("board", m.board).Msg("failed to refresh threads")
+			return nil
+		}
+
+		events := DiffThreadList(old, u.Value)
+		old = u.Value
+		if len(events) > 0 {
+			fn(events)
+		}
+		return nil
+	})
+}
+
+// listedThread is a thread in a threads.json with its page
+type listedThread struct {
+	ThreadSummary
+	page int
+}
+
+func (t listedThread) event(typ BoardEventType, board string, page, oldPage int) BoardEvent {
+	return BoardEvent{
+		Type:         typ,
+		Board:        board,
+		No:           t.No,
+		Page:         page,
+		OldPage:      oldPage,
+		LastModified: t.LastModified.Time,
+		Replies:      t.Replies,
+	}
+}
+
+// threadListThreads returns the threads in the list in order
+// and the position of each thread in the list
+func threadListThreads(tl *ThreadList) ([]listedThread, map[int]int) {
+	threads := make([]listedThread, 0)
+	order := make(map[int]int)
+	for _, p := range tl.Pages {
+		for _, t := range p.Threads {
+			order[t.No] = len(threads)
+			threads = append(threads, listedThread{ThreadSummary: t, page: p.Page})
+		}
+	}
+	return threads, order
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffThreadList(t *testing.T) {
+	old := &ThreadList{Board: "po", Pages: []ThreadListPage{
+		{Page: 1, Threads: []ThreadSummary{{No: 1}, {No: 2, LastModified: Timestamp{time.Unix(100, 0)}}, {No: 3}}},
+		{Page: 2, Threads: []ThreadSummary{{No: 4, LastModified: Timestamp{time.Unix(100, 0)}}, {No: 5}}},
+	}}
+	new := &ThreadList{Board: "po", Pages: []ThreadListPage{
+		{Page: 1, Threads: []ThreadSummary{{No: 1}, {No: 4, LastModified: Timestamp{time.Unix(200, 0)}, Replies: 1}, {No: 6}}},
+		{Page: 2, Threads: []ThreadSummary{{No: 2, LastModified: Timestamp{time.Unix(100, 0)}}, {No: 5}}},
+	}}
+
+	got := make([]string, 0)
+	for _, e := range DiffThreadList(old, new) {
+		got = append(got, fmt.Sprintf("%s %d %d->%d", e.Type, e.No, e.OldPage, e.Page))
+	}
+	want := []string{
+		"thread_pruned 3 1->0",
+		"thread_modified 4 2->1",
+		"thread_bumped 4 2->1",
+		"thread_moved 4 2->1",
+		"thread_added 6 0->1",
+		"thread_moved 2 1->2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong events\ngot:  %v\nwant: %v\n", got, want)
+	}
+}
+
+func TestBoardMonitor(t *testing.T) {
+	var requests int64
+	routes := map[string]string{
+		"/boards.json":     `{"boards": [{"board": "po"}]}`,
+		"/po/threads.json": `[{"page": 1, "threads": [{"no": 1, "last_modified": 100}, {"no": 2, "last_modified": 100}]}]`,
+	}
+	c := fakeClient(routes, &requests)
+
+	m, err := c.NewBoardMonitor("po", time.Millisecond)
+	if err != nil {
+		t.Errorf("failed to create monitor: %s\n", err)
+		return
+	}
+	if s, found := m.Thread(2); !found || s.LastModified.Unix() != 100 {
+		t.Errorf("thread missing from monitor: %+v\n", s)
+	}
+
+	routes["/po/threads.json"] = `[{"page": 1, "threads": [{"no": 2, "last_modified": 200}, {"no": 1, "last_modified": 100}]}]`
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var events []BoardEvent
+	err = m.Run(ctx, func(e []BoardEvent) {
+		events = e
+		cancel()
+	})
+	if err != context.Canceled {
+		t.Errorf("expected the monitor to be cancelled but got: %v\n", err)
+	}
+	if len(events) != 2 || events[0].Type != ThreadModified || events[1].Type != ThreadBumped || events[0].No != 2 {
+		t.Errorf("wrong events: %+v\n", events)
+	}
+	if s, _ := m.Thread(2); s.LastModified.Unix() != 200 {
+		t.Errorf("monitor not updated: %+v\n", s)
+	}
+}
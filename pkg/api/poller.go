@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"time"
+)
+
+// ErrStopPolling can be returned by the function given to Poller.Poll to stop polling without an error
+var ErrStopPolling = fmt.Errorf("stop polling")
+
+// RefreshFunc refreshes a resource, it should follow the same contract as the
+// Client's Refresh methods, e.g. Client.RefreshThread or Client.RefreshCatalog
+type RefreshFunc[T any] func(old T) (T, bool, error)
+
+// Update is delivered by a Poller when a resource changes or fails to refresh
+type Update[T any] struct {
+	Value T     // The newest version of the resource, if Err is set then this is the last version successfully fetched
+	Err   error // The error which occurred while refreshing the resource, e.g. ErrNotFound
+}
+
+// Poller refreshes a resource every interval, e.g.
+//
+//	p := api.NewPoller(thread, time.Minute, c.RefreshThread)
+//
+// The contract for every way of polling is the same:
+//   - The resource is first refreshed one interval after polling starts
+//   - An update is only delivered if the resource changed or if an error occurred,
+//     unchanged resources, i.e. 304s, are never delivered
+//   - Errors don't stop polling, the caller decides whether to stop, e.g. on ErrNotFound
+//   - Polling stops once the context is done
+//
+// A Poller should only be polled by one goroutine at a time
+type Poller[T any] struct {
+	current  T
+	interval time.Duration
+	refresh  RefreshFunc[T]
+}
+
+// NewPoller creates a poller which refreshes the resource every interval
+func NewPoller[T any](initial T, interval time.Duration, refresh RefreshFunc[T]) *Poller[T] {
+	return &Poller[T]{
+		current:  initial,
+		interval: interval,
+		refresh:  refresh,
+	}
+}
+
+// Current returns the newest version of the resource
+func (p *Poller[T]) Current() T {
+	return p.current
+}
+
+// Poll calls fn with each update until the context is done or fn returns an
+// error. The error is returned unless it's ErrStopPolling, if the context is
+// done then the context's error is returned
+func (p *Poller[T]) Poll(ctx context.Context, fn func(u Update[T]) error) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		u, ok := p.next()
+		if !ok {
+			continue
+		}
+		err := fn(u)
+		if err == ErrStopPolling {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// Updates delivers the updates on a channel which is closed once the context is done
+func (p *Poller[T]) Updates(ctx context.Context) <-chan Update[T] {
+	ch := make(chan Update[T])
+	go func() {
+		defer close(ch)
+		p.Poll(ctx, func(u Update[T]) error {
+			select {
+			case ch <- u:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	return ch
+}
+
+// All returns an iterator over the updates, iteration stops once the context
+// is done or the loop is broken out of, e.g.
+//
+//	for thread, err := range p.All(ctx) {
+//		...
+//	}
+func (p *Poller[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		p.Poll(ctx, func(u Update[T]) error {
+			if !yield(u.Value, u.Err) {
+				return ErrStopPolling
+			}
+			return nil
+		})
+	}
+}
+
+// next refreshes the resource and returns an update if one should be delivered
+func (p *Poller[T]) next() (Update[T], bool) {
+	v, mod, err := p.refresh(p.current)
+	if err != nil {
+		return Update[T]{Value: p.current, Err: err}, true
+	} else if !mod {
+		return Update[T]{}, false
+	}
+	p.current = v
+	return Update[T]{Value: v}, true
+}
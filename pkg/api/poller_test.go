@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeRefresh returns the results in order, an int of -1 is
+// unmodified and an int of 0 is an error, afterwards it's unmodified
+func fakeRefresh(results ...int) RefreshFunc[int] {
+	i := 0
+	return func(old int) (int, bool, error) {
+		if i >= len(results) {
+			return 0, false, nil
+		}
+		r := results[i]
+		i++
+		switch r {
+		case -1:
+			return 0, false, nil
+		case 0:
+			return 0, false, fmt.Errorf("failed to refresh")
+		default:
+			return r, true, nil
+		}
+	}
+}
+
+func TestPollerPoll(t *testing.T) {
+	p := NewPoller(1, time.Millisecond, fakeRefresh(-1, 2, 0, -1, 3, 4))
+
+	values := make([]int, 0)
+	errs := 0
+	err := p.Poll(context.Background(), func(u Update[int]) error {
+		if u.Err != nil {
+			errs++
+			if u.Value != 2 {
+				return fmt.Errorf("error update has value %d but expected the last value 2", u.Value)
+			}
+			return nil
+		}
+		values = append(values, u.Value)
+		if u.Value == 3 {
+			return ErrStopPolling
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("poll returned unexpected error: %s\n", err)
+	}
+	if !reflect.DeepEqual(values, []int{2, 3}) || errs != 1 || p.Current() != 3 {
+		t.Errorf("wrong updates, values: %v, errors: %d, current: %d\n", values, errs, p.Current())
+	}
+}
+
+func TestPollerUpdates(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := NewPoller(1, time.Millisecond, fakeRefresh(2, -1, 3))
+
+	values := make([]int, 0)
+	for u := range p.Updates(ctx) {
+		values = append(values, u.Value)
+		if len(values) == 2 {
+			cancel()
+		}
+	}
+	if !reflect.DeepEqual(values, []int{2, 3}) {
+		t.Errorf("wrong updates: %v\n", values)
+	}
+}
+
+func TestPollerAll(t *testing.T) {
+	p := NewPoller(1, time.Millisecond, fakeRefresh(2, 0, 3))
+
+	values := make([]int, 0)
+	for v, err := range p.All(context.Background()) {
+		if err != nil {
+			continue
+		}
+		values = append(values, v)
+		if v == 3 {
+			break
+		}
+	}
+	if !reflect.DeepEqual(values, []int{2, 3}) {
+		t.Errorf("wrong updates: %v\n", values)
+	}
+
+	// Polling stops once the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := NewPoller(1, time.Millisecond, fakeRefresh()).Poll(ctx, func(u Update[int]) error { return nil })
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded but got: %v\n", err)
+	}
+}
+
+func TestRefreshContract(t *testing.T) {
+	var requests int64
+	routes := map[string]string{
+		"/boards.json":           `{"boards": [{"board": "po"}]}`,
+		"/po/thread/570368.json": `{"posts": [{"no": 570368}]}`,
+	}
+	c := fakeClient(routes, &requests)
+
+	th, _, err := c.GetThread("po", 570368)
+	if err != nil {
+		t.Errorf("failed to get thread: %s\n", err)
+		return
+	}
+	since := th.time()
+
+	// Decoding errors aren't reported as modified and don't change the old thread
+	routes["/po/thread/570368.json"] = `{"posts": [`
+	n, mod, err := c.RefreshThread(th)
+	if err == nil || mod || n != nil || !th.time().Equal(since) {
+		t.Errorf("decode error broke the contract, thread: %v, modified: %v, err: %v\n", n, mod, err)
+	}
+
+	// Modified threads update the old thread's time
+	time.Sleep(time.Millisecond)
+	routes["/po/thread/570368.json"] = `{"posts": [{"no": 570368}, {"no": 570400}]}`
+	n, mod, err = c.RefreshThread(th)
+	if err != nil || !mod || len(n.Posts) != 2 || !th.time().After(since) || !th.time().Equal(n.time()) {
+		t.Errorf("modified thread broke the contract, thread: %v, modified: %v, err: %v\n", n, mod, err)
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FileURL returns the URL of the post's file, empty if the post has no file
+func (p *Post) FileURL(ssl bool) string {
+	if !p.HasFile {
+		return ""
+	}
+	return formatURL(ssl, MediaDomainA, p.Board, p.fileEndpoint())
+}
+
+// ThumbnailURL returns the URL of the post's thumbnail, empty if the post has no file
+func (p *Post) ThumbnailURL(ssl bool) string {
+	if !p.HasFile {
+		return ""
+	}
+	return formatURL(ssl, MediaDomainA, p.Board, p.thumbnailEndpoint())
+}
+
+// MobileImageURL returns the URL of the post's mobile optimised
+// image, empty if the post doesn't have one
+func (p *Post) MobileImageURL(ssl bool) string {
+	if !p.HasFile || !bool(p.MImg) {
+		return ""
+	}
+	return formatURL(ssl, MediaDomainA, p.Board, p.ImageID.String()+"m.jpg")
+}
+
+// FlagURL returns the URL of the poster's flag. Board flags are preferred
+// over troll flags which are preferred over country flags, if the post has
+// no flag then the URL is empty
+func (p *Post) FlagURL(ssl bool) string {
+	switch {
+	case p.BoardFlag != "":
+		return formatURL(ssl, StaticDomain, "image/flags/"+p.Board, strings.ToLower(p.BoardFlag)+".gif")
+	case p.TrollCountry != "":
+		return formatURL(ssl, StaticDomain, trollFlagDir, strings.ToLower(p.TrollCountry)+".gif")
+	case p.Country != "":
+		return formatURL(ssl, StaticDomain, countryFlagDir, strings.ToLower(p.Country)+".gif")
+	}
+	return ""
+}
+
+// SpoilerURL returns the URL of the image shown in place of the post's
+// spoilered file, empty if the file isn't spoilered
+func (p *Post) SpoilerURL(ssl bool) string {
+	if !p.HasFile || !bool(p.ImageSpoiler) {
+		return ""
+	}
+	if p.CustomSpoiler > 0 {
+		return formatURL(ssl, StaticDomain, "image", customSpoiler(p.Board, p.CustomSpoiler)+".png")
+	}
+	return formatURL(ssl, StaticDomain, "image", "spoiler.png")
+}
+
+// ThreadURL returns the permalink of the thread the post is in,
+// for the OP the thread's SemanticURL is included
+func (p *Post) ThreadURL(ssl bool) string {
+	return p.link(false).URL(ssl)
+}
+
+// PostURL returns the permalink of the post, i.e. its thread's URL
+// with an anchor to the post
+func (p *Post) PostURL(ssl bool) string {
+	return p.link(true).URL(ssl)
+}
+
+// link returns the link to the post's thread and if
+// needed an anchor to the post itself
+func (p *Post) link(anchor bool) Link {
+	l := Link{Board: p.Board, Thread: p.RepliesTo, Slug: p.SemanticURL}
+	if l.Thread == 0 {
+		l.Thread = p.No
+	}
+	if anchor {
+		l.Post = p.No
+	}
+	return l
+}
+
+// Directories of the static domain which flags are served from
+const (
+	countryFlagDir = "image/country"
+	trollFlagDir   = "image/country/troll"
+)
+
+func (p *Post) fileEndpoint() string {
+	return p.ImageID.String() + p.Ext
+}
+
+func (p *Post) thumbnailEndpoint() string {
+	return p.ImageID.String() + "s.jpg"
+}
+
+func customSpoiler(board string, num int) string {
+	return fmt.Sprintf("spoiler-%s%d", strings.Trim(board, "/"), num)
+}
@@ -0,0 +1,49 @@
+package api
+
+import "testing"
+
+func TestPostURLs(t *testing.T) {
+	op := &Post{
+		Board:         "po",
+		HasFile:       true,
+		No:            570368,
+		ImageID:       "1546293948883",
+		Ext:           ".png",
+		MImg:          true,
+		Country:       "GB",
+		ImageSpoiler:  true,
+		CustomSpoiler: 2,
+		SemanticURL:   "welcome-to-po",
+	}
+	reply := &Post{Board: "po", No: 570400, RepliesTo: 570368, TrollCountry: "TM", Country: "GB"}
+	flagged := &Post{Board: "pol", No: 1, HasFile: true, ImageID: "1", Ext: ".jpg", ImageSpoiler: true, BoardFlag: "AN", Country: "GB"}
+
+	tests := []struct {
+		name, got, want string
+	}{
+		{"file", op.FileURL(true), "https://i.4cdn.org/po/1546293948883.png"},
+		{"file without ssl", op.FileURL(false), "http://i.4cdn.org/po/1546293948883.png"},
+		{"file without file", reply.FileURL(true), ""},
+		{"thumbnail", op.ThumbnailURL(true), "https://i.4cdn.org/po/1546293948883s.jpg"},
+		{"thumbnail without file", reply.ThumbnailURL(true), ""},
+		{"mobile image", op.MobileImageURL(true), "https://i.4cdn.org/po/1546293948883m.jpg"},
+		{"mobile image without m_img", flagged.MobileImageURL(true), ""},
+		{"country flag", op.FlagURL(true), "https://s.4cdn.org/image/country/gb.gif"},
+		{"troll flag", reply.FlagURL(true), "https://s.4cdn.org/image/country/troll/tm.gif"},
+		{"board flag", flagged.FlagURL(true), "https://s.4cdn.org/image/flags/pol/an.gif"},
+		{"no flag", (&Post{Board: "po"}).FlagURL(true), ""},
+		{"custom spoiler", op.SpoilerURL(true), "https://s.4cdn.org/image/spoiler-po2.png"},
+		{"default spoiler", flagged.SpoilerURL(true), "https://s.4cdn.org/image/spoiler.png"},
+		{"no spoiler", reply.SpoilerURL(true), ""},
+		{"op thread", op.ThreadURL(true), "https://boards.4chan.org/po/thread/570368/welcome-to-po"},
+		{"op post", op.PostURL(false), "http://boards.4chan.org/po/thread/570368/welcome-to-po#p570368"},
+		{"reply thread", reply.ThreadURL(true), "https://boards.4chan.org/po/thread/570368"},
+		{"reply post", reply.PostURL(true), "https://boards.4chan.org/po/thread/570368#p570400"},
+	}
+
+	for _, tc := range tests {
+		if tc.got != tc.want {
+			t.Errorf("%s: expected %s but got %s\n", tc.name, tc.want, tc.got)
+		}
+	}
+}
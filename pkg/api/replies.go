@@ -0,0 +1,159 @@
+package api
+
+import "sort"
+
+// Reference is a link to a post in another thread or on another board
+type Reference struct {
+	Board  string // The board of the post
+	Thread int    // The thread of the post, zero if unknown
+	Post   int    // The ID of the post
+}
+
+// ReplyGraph records which posts in a thread quote each other
+type ReplyGraph struct {
+	order     map[int]int         // Position of each post in the thread
+	quotes    map[int][]int       // Posts in the thread which each post quotes
+	backlinks map[int][]int       // Posts in the thread which quote each post
+	external  map[int][]Reference // Posts outside the thread which each post quotes
+}
+
+// ReplyGraph extracts the >>No references from every post's comment
+// and builds a graph of which posts quote each other
+func (t *Thread) ReplyGraph() *ReplyGraph {
+	g := &ReplyGraph{
+		order:     make(map[int]int),
+		quotes:    make(map[int][]int),
+		backlinks: make(map[int][]int),
+		external:  make(map[int][]Reference),
+	}
+	for i, p := range t.Posts {
+		g.order[p.No] = i
+	}
+
+	for _, p := range t.Posts {
+		nodes, err := p.ParseComment()
+		if err != nil {
+			continue
+		}
+
+		seen := make(map[Reference]struct{})
+		WalkComment(nodes, func(n *CommentNode) {
+			if (n.Type != QuoteLinkNode && n.Type != CrossBoardLinkNode) || n.Post == 0 {
+				return
+			}
+			ref := Reference{Board: n.Board, Thread: n.Thread, Post: n.Post}
+			if _, found := seen[ref]; found || n.Post == p.No {
+				return
+			}
+			seen[ref] = struct{}{}
+
+			if n.Board == t.Board && n.Thread == t.No {
+				g.quotes[p.No] = append(g.quotes[p.No], n.Post)
+				g.backlinks[n.Post] = append(g.backlinks[n.Post], p.No)
+			} else {
+				g.external[p.No] = append(g.external[p.No], ref)
+			}
+		})
+	}
+
+	return g
+}
+
+// Quotes returns the posts in the thread which the post quotes
+func (g *ReplyGraph) Quotes(no int) []int {
+	return g.quotes[no]
+}
+
+// Backlinks returns the posts in the thread which quote the post
+func (g *ReplyGraph) Backlinks(no int) []int {
+	return g.backlinks[no]
+}
+
+// CrossThread returns the posts in other threads or on other boards which the post quotes
+func (g *ReplyGraph) CrossThread(no int) []Reference {
+	return g.external[no]
+}
+
+// IsRoot returns whether the post doesn't quote any posts in the thread
+func (g *ReplyGraph) IsRoot(no int) bool {
+	return len(g.quotes[no]) == 0
+}
+
+// IsLeaf returns whether the post isn't quoted by any posts in the thread
+func (g *ReplyGraph) IsLeaf(no int) bool {
+	return len(g.backlinks[no]) == 0
+}
+
+// Roots returns the posts which don't quote any posts in the thread, in thread order
+func (g *ReplyGraph) Roots() []int {
+	return g.filter(g.IsRoot)
+}
+
+// Leaves returns the posts which aren't quoted by any posts in the thread, in thread order
+func (g *ReplyGraph) Leaves() []int {
+	return g.filter(g.IsLeaf)
+}
+
+// Conversation returns the chain of posts the post is part of, this is the
+// post, every post it quotes directly or indirectly and every post which
+// replies to it directly or indirectly. The posts are in thread order
+func (g *ReplyGraph) Conversation(no int) []int {
+	visited := map[int]struct{}{no: {}}
+
+	var walk func(no int, next map[int][]int)
+	walk = func(no int, next map[int][]int) {
+		for _, n := range next[no] {
+			if _, found := visited[n]; !found {
+				visited[n] = struct{}{}
+				walk(n, next)
+			}
+		}
+	}
+	walk(no, g.quotes)
+	walk(no, g.backlinks)
+
+	chain := make([]int, 0, len(visited))
+	for n := range visited {
+		chain = append(chain, n)
+	}
+	g.sort(chain)
+	return chain
+}
+
+// Backlinks returns the posts in the thread which quote the post, use
+// ReplyGraph() instead when querying multiple posts
+func (t *Thread) Backlinks(no int) []int {
+	return t.ReplyGraph().Backlinks(no)
+}
+
+// Quotes returns the posts in the thread which the post quotes, use
+// ReplyGraph() instead when querying multiple posts
+func (t *Thread) Quotes(no int) []int {
+	return t.ReplyGraph().Quotes(no)
+}
+
+func (g *ReplyGraph) filter(fn func(no int) bool) []int {
+	posts := make([]int, 0)
+	for no := range g.order {
+		if fn(no) {
+			posts = append(posts, no)
+		}
+	}
+	g.sort(posts)
+	return posts
+}
+
+// sort orders the posts by their position in the thread, posts
+// not in the thread are ordered by their ID after those which are
+func (g *ReplyGraph) sort(posts []int) {
+	sort.Slice(posts, func(i, j int) bool {
+		oi, iFound := g.order[posts[i]]
+		oj, jFound := g.order[posts[j]]
+		if iFound && jFound {
+			return oi < oj
+		} else if iFound != jFound {
+			return iFound
+		}
+		return posts[i] < posts[j]
+	})
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func replyThread() *Thread {
+	quote := func(no string) string {
+		return `<a href="#p` + no + `" class="quotelink">&gt;&gt;` + no + `</a><br>`
+	}
+
+	return &Thread{
+		Board: "po",
+		No:    1,
+		Posts: []*Post{
+			{Board: "po", No: 1, Comment: "op"},
+			{Board: "po", No: 2, RepliesTo: 1, Comment: quote("1") + quote("1") + "double quote"},
+			{Board: "po", No: 3, RepliesTo: 1, Comment: quote("2") + quote("3") + "self quote"},
+			{Board: "po", No: 4, RepliesTo: 1, Comment: "unrelated " +
+				`<a href="/po/thread/9#p10" class="quotelink">&gt;&gt;10</a>` +
+				`<a href="/g/thread/20#p21" class="quotelink">&gt;&gt;&gt;/g/21</a>` +
+				`<a href="//boards.4channel.org/g/" class="quotelink">&gt;&gt;&gt;/g/</a>`},
+			{Board: "po", No: 5, RepliesTo: 1, Comment: quote("3")},
+			{Board: "po", No: 6, RepliesTo: 1, Comment: quote("1")},
+		},
+	}
+}
+
+func TestReplyGraph(t *testing.T) {
+	th := replyThread()
+	g := th.ReplyGraph()
+
+	check := func(name string, got, want interface{}) {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s incorrect, got: %v, want: %v\n", name, got, want)
+		}
+	}
+
+	check("quotes of 2", g.Quotes(2), []int{1})
+	check("quotes of 3", g.Quotes(3), []int{2})
+	check("backlinks of 1", g.Backlinks(1), []int{2, 6})
+	check("backlinks of 3", th.Backlinks(3), []int{5})
+	check("quotes of 5", th.Quotes(5), []int{3})
+	check("cross thread of 4", g.CrossThread(4), []Reference{
+		{Board: "po", Thread: 9, Post: 10},
+		{Board: "g", Thread: 20, Post: 21},
+	})
+	check("roots", g.Roots(), []int{1, 4})
+	check("leaves", g.Leaves(), []int{4, 5, 6})
+	check("conversation of 3", g.Conversation(3), []int{1, 2, 3, 5})
+	check("conversation of 4", g.Conversation(4), []int{4})
+
+	if !g.IsRoot(1) || g.IsRoot(2) {
+		t.Errorf("root detection incorrect\n")
+	}
+	if !g.IsLeaf(6) || g.IsLeaf(1) {
+		t.Errorf("leaf detection incorrect\n")
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestWalkBoard(t *testing.T) {
+	var requests int64
+	c := fakeClient(map[string]string{
+		"/boards.json": `{"boards": [{"board": "po", "pages": 3}]}`,
+		"/po/1.json":   `{"threads": [{"posts": [{"no": 1, "sticky": 1}, {"no": 5, "resto": 1}]}, {"posts": [{"no": 2, "fsize": 10}]}]}`,
+		"/po/2.json":   `{"threads": [{"posts": [{"no": 1, "sticky": 1}]}, {"posts": [{"no": 3}, {"no": 6, "resto": 3}]}, {"posts": [{"no": 2}]}]}`,
+		// Page 3 is missing so it 404s
+	}, &requests)
+
+	threads := make([]int, 0)
+	posts := 0
+	err := c.WalkBoard(context.Background(), "po", func(th *Thread) error {
+		threads = append(threads, th.No)
+		posts += len(th.Posts)
+		if th.Board != "po" {
+			return fmt.Errorf("thread %d has no board", th.No)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("failed to walk board: %s\n", err)
+	}
+	if !reflect.DeepEqual(threads, []int{1, 2, 3}) || posts != 5 {
+		t.Errorf("walked threads %v with %d posts but expected [1 2 3] with 5 posts\n", threads, posts)
+	}
+
+	// Walking stops early without an error
+	threads = threads[:0]
+	err = c.WalkBoard(context.Background(), "po", func(th *Thread) error {
+		threads = append(threads, th.No)
+		return ErrStopWalk
+	})
+	if err != nil || len(threads) != 1 {
+		t.Errorf("walk didn't stop early, walked %v, err: %v\n", threads, err)
+	}
+
+	// Errors from fn are returned
+	stop := fmt.Errorf("stop")
+	err = c.WalkBoard(context.Background(), "po", func(th *Thread) error { return stop })
+	if err != stop {
+		t.Errorf("expected error from fn but got: %v\n", err)
+	}
+
+	// Cancelled contexts stop the walk before any requests
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	before := requests
+	err = c.WalkBoard(ctx, "po", func(th *Thread) error { return nil })
+	if err != context.Canceled || requests != before {
+		t.Errorf("walk wasn't cancelled, err: %v\n", err)
+	}
+}
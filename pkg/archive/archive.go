@@ -0,0 +1,149 @@
+// Package archive saves 4chan threads, their files and their HTML page
+// to the local filesystem
+package archive
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+
+	"github.com/fiwippi/crow/pkg/api"
+)
+
+// Format decides which representations of a thread are saved
+type Format int
+
+const (
+	FormatHTML Format = 1 << iota // The thread's HTML page with links redirected to local files, saved as thread.html
+	FormatJSON                    // The thread as returned by the api, saved as thread.json
+)
+
+// ParseFormat parses a comma separated list of formats, e.g. "html,json"
+func ParseFormat(s string) (Format, error) {
+	var f Format
+	for _, name := range strings.Split(s, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "html":
+			f |= FormatHTML
+		case "json":
+			f |= FormatJSON
+		default:
+			return 0, fmt.Errorf("invalid format: %q", name)
+		}
+	}
+	return f, nil
+}
+
+// Options configure how an Archiver saves threads
+type Options struct {
+	Dst         string          // Destination dir, threads are saved to "<Dst>/4chan/<board>/<no>/"
+	Overwrite   bool            // Whether to overwrite files which already exist
+	ValidateMD5 bool            // Whether to validate the MD5 hash of files
+	FilesOnly   bool            // Whether to archive only the files and not the thread itself, the files are still saved to images/
+	Format      Format          // Which representations of the thread to save, defaults to FormatHTML
+	Logger      *zerolog.Logger // Logger to use, if nil then nothing is logged
+	Observer    Observer        // Receives events describing the archiving progress, may be nil
+	Boards      *Boards         // Polls threads.json so watched threads are only requested when they change, if nil each thread is polled directly
+	Filter      Filter          // Decides which files are downloaded, by default every file is
+}
+
+func (o Options) dst() string {
+	if o.Dst == "" {
+		return "."
+	}
+	return strings.TrimSuffix(o.Dst, "/")
+}
+
+func (o Options) format() Format {
+	if o.Format == 0 {
+		return FormatHTML
+	}
+	return o.Format
+}
+
+// Archiver archives threads, it keeps track of the files it has
+// already saved for each thread so that it can be used to archive
+// the same thread multiple times as it updates
+type Archiver struct {
+	c    *api.Client
+	opts Options
+	log  zerolog.Logger
+
+	mu      sync.Mutex
+	threads map[string]*threadArchiver
+}
+
+// New creates an Archiver which uses the client to download threads
+func New(c *api.Client, opts Options) *Archiver {
+	log := zerolog.Nop()
+	if opts.Logger != nil {
+		log = *opts.Logger
+	}
+
+	return &Archiver{
+		c:       c,
+		opts:    opts,
+		log:     log,
+		threads: make(map[string]*threadArchiver),
+	}
+}
+
+// Archive saves the thread and all of its files. It's safe to call
+// Archive concurrently for different threads
+func (a *Archiver) Archive(t *api.Thread) error {
+	// Ensure valid thread
+	if t == nil {
+		return fmt.Errorf("thread is invalid since it's nil")
+	}
+
+	ta := a.thread(t.Board, t.No)
+	ta.run.Lock()
+	defer ta.run.Unlock()
+
+	return ta.archive(t)
+}
+
+// ArchiveContext is the same as Archive except the thread's requests are
+// cancelled once the context is done, files which have already been
+// downloaded are still saved before it returns
+func (a *Archiver) ArchiveContext(ctx context.Context, t *api.Thread) error {
+	if t == nil {
+		return fmt.Errorf("thread is invalid since it's nil")
+	}
+
+	ta := a.thread(t.Board, t.No)
+	ta.run.Lock()
+	defer ta.run.Unlock()
+
+	return ta.archiveContext(ctx, t)
+}
+
+// Dir returns the directory the thread is saved to
+func (a *Archiver) Dir(board string, no int) string {
+	return fmt.Sprintf("%s/4chan/%s/%d/", a.opts.dst(), strings.Trim(board, "/"), no)
+}
+
+// thread returns the archiver for the specific thread, creating it if needed
+func (a *Archiver) thread(board string, no int) *threadArchiver {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := fmt.Sprintf("%s/%d", board, no)
+	ta, found := a.threads[key]
+	if !found {
+		ta = newThreadArchiver(a.c, a.opts, a.log, board, no)
+		a.threads[key] = ta
+	}
+	return ta
+}
+
+// forget removes the saved state for the thread
+func (a *Archiver) forget(board string, no int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.threads, fmt.Sprintf("%s/%d", board, no))
+}
@@ -0,0 +1,58 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Format
+		err  bool
+	}{
+		{"html", FormatHTML, false},
+		{"json", FormatJSON, false},
+		{"html,json", FormatHTML | FormatJSON, false},
+		{" JSON , html ", FormatHTML | FormatJSON, false},
+		{"xml", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tc := range tests {
+		f, err := ParseFormat(tc.in)
+		if (err != nil) != tc.err {
+			t.Errorf("format %q returned unexpected error: %v\n", tc.in, err)
+			continue
+		}
+		if f != tc.want {
+			t.Errorf("format %q parsed as %d but expected %d\n", tc.in, f, tc.want)
+		}
+	}
+}
+
+func TestWriteAtomic(t *testing.T) {
+	path := t.TempDir() + "/thread.html"
+
+	err := writeAtomic(path, func(w io.Writer) error {
+		_, err := w.Write([]byte("thread"))
+		return err
+	})
+	if b, _ := os.ReadFile(path); err != nil || string(b) != "thread" {
+		t.Errorf("file not written, contents: %q, err: %v\n", b, err)
+	}
+
+	// Failed writes leave the existing file untouched and remove the temporary file
+	err = writeAtomic(path, func(w io.Writer) error {
+		w.Write([]byte("half"))
+		return fmt.Errorf("stopped")
+	})
+	if b, _ := os.ReadFile(path); err == nil || string(b) != "thread" {
+		t.Errorf("file changed by failed write, contents: %q, err: %v\n", b, err)
+	}
+	if fileExists(path + ".tmp") {
+		t.Errorf("temporary file not removed\n")
+	}
+}
@@ -0,0 +1,230 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/net/html"
+
+	"github.com/fiwippi/crow/pkg/api"
+)
+
+// threadArchiver archives a single thread, it's kept by the Archiver
+// between runs so files are only downloaded once
+type threadArchiver struct {
+	c          *api.Client
+	ctx        context.Context // Cancels archiving, it's the context of c's requests
+	log        zerolog.Logger
+	wg         *sync.WaitGroup
+	run        sync.Mutex          // Ensures the thread is only archived once at a time
+	mu         sync.Mutex          // Guards downloaded
+	downloaded map[string]struct{} // Keeps track of files which have already been downloaded
+	obs        Observer            // Receives progress events, may be nil
+	board      string              // Board of the thread being archived
+	no         int                 // OP ID of the thread being archived
+	saved      int64               // Total bytes saved to disk
+	failed     int64               // Number of files which failed to save
+
+	// Settings for downloading files
+	overwrite bool   // Whether to overwrite files which already exist
+	md5       bool   // Whether to validate MD5 of downloaded images
+	filesOnly bool   // Whether to only save the images of the thread
+	format    Format // Which representations of the thread to save
+	filter    Filter // Decides which files are downloaded
+
+	// Output directories
+	outputDir string // Dirs where to save files
+	thumbDir  string // Sub-dir to save thumbnails
+	imgDir    string // Sub-dir to save images
+	cssDir    string // Sub-dir to save css
+	jsDir     string // Sub-dir to save javascript
+	assetDir  string // Sub-dir to save static assets
+}
+
+func newThreadArchiver(c *api.Client, opts Options, log zerolog.Logger, board string, no int) *threadArchiver {
+	dst := fmt.Sprintf("%s/4chan/%s/%d/", opts.dst(), board, no)
+
+	return &threadArchiver{
+		c:          c,
+		ctx:        context.Background(),
+		log:        log,
+		wg:         &sync.WaitGroup{},
+		downloaded: make(map[string]struct{}),
+		obs:        opts.Observer,
+		board:      board,
+		no:         no,
+		overwrite:  opts.Overwrite,
+		md5:        opts.ValidateMD5,
+		filesOnly:  opts.FilesOnly,
+		format:     opts.format(),
+		filter:     opts.Filter,
+		outputDir:  dst,
+		thumbDir:   fmt.Sprintf("%s%s/", dst, "thumbs"),
+		imgDir:     fmt.Sprintf("%s%s/", dst, "images"),
+		cssDir:     fmt.Sprintf("%s%s/", dst, "css"),
+		jsDir:      fmt.Sprintf("%s%s/", dst, "js"),
+		assetDir:   fmt.Sprintf("%s%s/", dst, "assets"),
+	}
+}
+
+// archive saves the thread and its files to the output directory, if it fails
+// then the observer is notified unless the thread's requests were cancelled
+func (a *threadArchiver) archive(t *api.Thread) (err error) {
+	defer func() {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			a.emit(Event{Type: ArchiveFailed, Err: err})
+		}
+	}()
+
+	atomic.StoreInt64(&a.saved, 0)
+	atomic.StoreInt64(&a.failed, 0)
+	start := time.Now()
+
+	err = os.MkdirAll(a.outputDir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	// Begin downloading the thread images
+	a.log.Info().Int("no", t.No).Str("board", t.Board).Msg("downloading files")
+	a.wg.Add(1)
+	go a.dlThreadFiles(t)
+
+	if !a.filesOnly {
+		if a.format&FormatJSON != 0 {
+			a.log.Info().Int("no", t.No).Str("board", t.Board).Msg("saving JSON...")
+			err = a.saveJSON(t)
+			if err != nil {
+				a.log.Error().Err(err).Msg("failed to save thread json")
+				a.wg.Wait()
+				return err
+			}
+		}
+
+		if a.format&FormatHTML != 0 {
+			err = a.saveHTML(t)
+			if err != nil {
+				a.wg.Wait()
+				return err
+			}
+		}
+	}
+
+	// Wait until everything is downloaded
+	a.log.Info().Int("no", t.No).Str("board", t.Board).Msg("ensuring downloading completed...")
+	a.wg.Wait()
+	end := time.Since(start).Round(time.Second)
+	a.emit(Event{
+		Type:     ArchiveDone,
+		Bytes:    atomic.LoadInt64(&a.saved),
+		Failed:   int(atomic.LoadInt64(&a.failed)),
+		Duration: end,
+	})
+
+	a.log.Info().Int("no", t.No).Str("time_taken", end.String()).Str("board", t.Board).Msg("archiving done!")
+	return nil
+}
+
+// archiveContext archives the thread using a client whose requests are
+// cancelled once the context is done, run must be locked by the caller
+func (a *threadArchiver) archiveContext(ctx context.Context, t *api.Thread) error {
+	c, prev := a.c, a.ctx
+	a.c, a.ctx = c.WithContext(ctx), ctx
+	defer func() { a.c, a.ctx = c, prev }()
+
+	return a.archive(t)
+}
+
+// saveHTML downloads the thread's HTML page, redirects its links to
+// local files and writes it to the output directory
+func (a *threadArchiver) saveHTML(t *api.Thread) error {
+	// Download the thread's HTML page
+	data, err := a.c.GetThreadHTML(t)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+
+	// Save the icons to the asset dir
+	a.log.Info().Int("no", t.No).Str("board", t.Board).Msg("saving icons")
+	a.wg.Add(1)
+	go a.saveIcons(t)
+
+	// Process the html by linking to local assets
+	root, err := a.formatHTML(data, t)
+	if err != nil {
+		a.log.Error().Err(err).Msg("failed to parse html doc")
+		return err
+	}
+
+	// Write the html to a file
+	a.log.Info().Int("no", t.No).Str("board", t.Board).Msg("rendering HTML...")
+	err = writeAtomic(a.outputDir+"thread.html", func(w io.Writer) error {
+		return html.Render(w, root)
+	})
+	if err != nil {
+		a.log.Error().Err(err).Msg("failed to render html to file")
+		return err
+	}
+	a.log.Info().Int("no", t.No).Str("board", t.Board).Msg("done rendering...")
+
+	return nil
+}
+
+// saveJSON writes the thread as JSON to the output directory
+func (a *threadArchiver) saveJSON(t *api.Thread) error {
+	b, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeAtomic(a.outputDir+"thread.json", func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
+
+// cancelled returns whether archiving was cancelled, e.g. crow is shutting
+// down, files which fail to download because of it aren't reported as failed
+func (a *threadArchiver) cancelled(err error) bool {
+	return a.ctx.Err() != nil || errors.Is(err, context.Canceled)
+}
+
+// seen returns whether the key has been marked as downloaded
+func (a *threadArchiver) seen(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, found := a.downloaded[key]
+	return found
+}
+
+// visit marks the key as downloaded and returns whether
+// this is the first time it has been visited
+func (a *threadArchiver) visit(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, found := a.downloaded[key]
+	if !found {
+		a.downloaded[key] = struct{}{}
+	}
+	return !found
+}
+
+// emit sends the event to the observer if one exists
+func (a *threadArchiver) emit(e Event) {
+	if a.obs == nil {
+		return
+	}
+	e.Board = a.board
+	e.No = a.no
+	a.obs.Observe(e)
+}
@@ -0,0 +1,147 @@
+package archive
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fiwippi/crow/pkg/api"
+)
+
+// BackfillResult summarises a backfill
+type BackfillResult struct {
+	Total    int // Number of threads in the board's archive
+	Archived int // Number of threads archived
+	Skipped  int // Number of threads skipped since they were already archived
+	Failed   int // Number of threads which failed to archive or had files which failed to save
+}
+
+// Backfill archives every thread in the board's archive.json which hasn't already
+// been archived locally. Threads which are archived without any failed files are
+// recorded in "<Dst>/4chan/<board>/.backfill" so the next backfill skips them and
+// can resume where the last one stopped. Threads which were already saved, e.g. by
+// Watch, are also skipped, see archivedLocally. The client's rate limits apply so
+// backfilling a board can take a long time, it stops once the context is done and
+// the thread being archived when it's done isn't counted as failed
+func (a *Archiver) Backfill(ctx context.Context, board string) (BackfillResult, error) {
+	board = strings.Trim(board, "/")
+	c := a.c.WithContext(ctx)
+
+	arc, _, err := c.GetArchive(board)
+	if err != nil {
+		return BackfillResult{}, err
+	}
+	res := BackfillResult{Total: len(arc.PostIDs)}
+
+	path := fmt.Sprintf("%s/4chan/%s/.backfill", a.opts.dst(), board)
+	done, err := readBackfilled(path)
+	if err != nil {
+		return res, err
+	}
+	state, err := openBackfilled(path)
+	if err != nil {
+		return res, err
+	}
+	defer state.Close()
+
+	for i, no := range arc.PostIDs {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+
+		if _, found := done[no]; found || a.archivedLocally(board, no) {
+			res.Skipped++
+			continue
+		}
+		a.log.Info().Int("no", no).Str("board", board).Msg(fmt.Sprintf("backfilling thread... [%d/%d]", i+1, res.Total))
+
+		t, _, err := c.GetThread(board, no)
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return res, ctx.Err()
+		} else if err != nil {
+			// Threads may have been deleted from the archive since it was fetched
+			a.log.Error().Err(err).Int("no", no).Str("board", board).Msg("failed to get thread")
+			res.Failed++
+			continue
+		}
+
+		failed, err := a.archiveOnce(ctx, t)
+		if ctx.Err() != nil {
+			// The thread is only partly archived so it's retried by the next backfill
+			return res, ctx.Err()
+		} else if err != nil || failed > 0 {
+			a.log.Error().Err(err).Int("no", no).Str("board", board).Int("failed_files", failed).Msg("failed to backfill thread")
+			res.Failed++
+			continue
+		}
+		_, err = fmt.Fprintln(state, no)
+		if err != nil {
+			return res, err
+		}
+		res.Archived++
+	}
+
+	return res, nil
+}
+
+// archiveOnce archives the thread, returns how many of its files
+// failed to save and then forgets it since it won't be archived again
+func (a *Archiver) archiveOnce(ctx context.Context, t *api.Thread) (int, error) {
+	defer a.forget(t.Board, t.No)
+
+	ta := a.thread(t.Board, t.No)
+	ta.run.Lock()
+	defer ta.run.Unlock()
+
+	err := ta.archiveContext(ctx, t)
+	return int(atomic.LoadInt64(&ta.failed)), err
+}
+
+// archivedLocally returns whether the thread has been saved. If its thread.json
+// was saved then it must show the thread was archived since it may have been
+// saved before it stopped being updated, otherwise its thread.html must exist
+// since that's all that's saved by default
+func (a *Archiver) archivedLocally(board string, no int) bool {
+	t, err := a.loadThread(board, no)
+	if err == nil {
+		return bool(t.Archived)
+	}
+	return os.IsNotExist(err) && fileExists(a.Dir(board, no)+"thread.html")
+}
+
+// readBackfilled reads the thread numbers in the backfill state file
+func readBackfilled(path string) (map[int]struct{}, error) {
+	done := make(map[int]struct{})
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return done, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// Lines which can't be parsed are ignored so the thread is retried
+		no, err := strconv.Atoi(strings.TrimSpace(s.Text()))
+		if err == nil {
+			done[no] = struct{}{}
+		}
+	}
+	return done, s.Err()
+}
+
+// openBackfilled opens the backfill state file for appending
+func openBackfilled(path string) (*os.File, error) {
+	err := os.MkdirAll(path[:strings.LastIndex(path, "/")], os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
@@ -0,0 +1,67 @@
+package archive
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/fiwippi/crow/pkg/api"
+)
+
+func TestBackfillState(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/4chan/po/.backfill"
+
+	done, err := readBackfilled(path)
+	if err != nil || len(done) != 0 {
+		t.Errorf("missing state file should be empty, got: %v, err: %v\n", done, err)
+	}
+
+	f, err := openBackfilled(path)
+	if err != nil {
+		t.Errorf("failed to open state file: %s\n", err)
+		return
+	}
+	fmt.Fprintln(f, 570368)
+	fmt.Fprintln(f, "garbage")
+	fmt.Fprintln(f, 570400)
+	f.Close()
+
+	done, err = readBackfilled(path)
+	if err != nil || len(done) != 2 {
+		t.Errorf("wrong threads read from state file: %v, err: %v\n", done, err)
+	}
+	for _, no := range []int{570368, 570400} {
+		if _, found := done[no]; !found {
+			t.Errorf("thread %d missing from state file\n", no)
+		}
+	}
+}
+
+func TestArchivedLocally(t *testing.T) {
+	a := New(api.DefaultClient(), Options{Dst: t.TempDir(), Format: FormatJSON})
+
+	if a.archivedLocally("po", 1) {
+		t.Errorf("thread without a thread.json is archived\n")
+	}
+
+	for no, archived := range map[int]bool{1: true, 2: false} {
+		ta := a.thread("po", no)
+		os.MkdirAll(ta.outputDir, os.ModePerm)
+		err := ta.saveJSON(&api.Thread{Board: "po", No: no, Archived: api.Bool(archived)})
+		if err != nil {
+			t.Errorf("failed to save thread: %s\n", err)
+			return
+		}
+		if a.archivedLocally("po", no) != archived {
+			t.Errorf("thread %d archived locally should be %v\n", no, archived)
+		}
+	}
+	// Threads saved as HTML only are archived
+	ta := a.thread("po", 3)
+	os.MkdirAll(ta.outputDir, os.ModePerm)
+	os.WriteFile(ta.outputDir+"thread.html", []byte("<html></html>"), 0644)
+	if !a.archivedLocally("po", 3) {
+		t.Errorf("thread with only a thread.html isn't archived\n")
+	}
+}
@@ -0,0 +1,142 @@
+package archive
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/fiwippi/crow/pkg/api"
+)
+
+// Boards polls the threads.json of every board which has threads being watched,
+// watchers use it to only request a thread when its last modified time advances
+// so watching many threads on the same board costs one request per interval. It
+// can be shared between Archivers by setting it in their Options
+type Boards struct {
+	c        *api.Client
+	interval time.Duration
+	log      zerolog.Logger
+
+	mu     sync.Mutex
+	boards map[string]*watchedBoard
+}
+
+// NewBoards creates a Boards which polls threads.json every interval
+func NewBoards(c *api.Client, interval time.Duration, logger *zerolog.Logger) *Boards {
+	log := zerolog.Nop()
+	if logger != nil {
+		log = *logger
+	}
+
+	return &Boards{
+		c:        c,
+		interval: interval,
+		log:      log,
+		boards:   make(map[string]*watchedBoard),
+	}
+}
+
+// watchedBoard is a board whose threads.json is being polled
+type watchedBoard struct {
+	refs   int                // Number of threads being watched on the board
+	cancel context.CancelFunc // Stops polling the board
+
+	mu      sync.RWMutex
+	threads map[int]api.ThreadSummary // The threads in the newest threads.json
+}
+
+// watch starts polling the board if it isn't already being polled,
+// release must be called once the thread is no longer being watched
+func (b *Boards) watch(board string) (*watchedBoard, error) {
+	board = strings.Trim(board, "/")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wb, found := b.boards[board]
+	if found {
+		wb.refs++
+		return wb, nil
+	}
+
+	m, err := b.c.NewBoardMonitor(board, b.interval)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	wb = &watchedBoard{refs: 1, cancel: cancel}
+	wb.update(m.Threads())
+	b.boards[board] = wb
+
+	b.log.Debug().Str("board", board).Msg("polling threads.json")
+	go m.Run(ctx, func(events []api.BoardEvent) {
+		// The events are only used to find out that the list has
+		// changed since the watchers compare the modified times
+		wb.update(m.Threads())
+	})
+
+	return wb, nil
+}
+
+// release stops polling the board once no threads on it are watched
+func (b *Boards) release(board string) {
+	board = strings.Trim(board, "/")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wb, found := b.boards[board]
+	if !found {
+		return
+	}
+	wb.refs--
+	if wb.refs <= 0 {
+		wb.cancel()
+		delete(b.boards, board)
+		b.log.Debug().Str("board", board).Msg("stopped polling threads.json")
+	}
+}
+
+// update replaces the threads with those in the threads.json
+func (wb *watchedBoard) update(tl *api.ThreadList) {
+	threads := make(map[int]api.ThreadSummary)
+	for _, p := range tl.Pages {
+		for _, t := range p.Threads {
+			threads[t.No] = t
+		}
+	}
+
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	wb.threads = threads
+}
+
+// changed returns whether the thread needs to be requested, this is when its
+// last modified time is after seen or when it isn't in threads.json, e.g.
+// it's been archived or deleted. If the thread changed then seen is updated
+func (wb *watchedBoard) changed(no int, seen *time.Time) bool {
+	wb.mu.RLock()
+	defer wb.mu.RUnlock()
+
+	t, found := wb.threads[no]
+	if !found {
+		return true
+	}
+	if !t.LastModified.After(*seen) {
+		return false
+	}
+	*seen = t.LastModified.Time
+	return true
+}
+
+// lastModified returns the thread's last modified
+// time or the zero time if it isn't in threads.json
+func (wb *watchedBoard) lastModified(no int) time.Time {
+	wb.mu.RLock()
+	defer wb.mu.RUnlock()
+
+	return wb.threads[no].LastModified.Time
+}
@@ -0,0 +1,35 @@
+package archive
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fiwippi/crow/pkg/api"
+)
+
+func TestWatchedBoardChanged(t *testing.T) {
+	wb := &watchedBoard{}
+	wb.update(&api.ThreadList{Pages: []api.ThreadListPage{
+		{Page: 1, Threads: []api.ThreadSummary{{No: 1, LastModified: api.Timestamp{Time: time.Unix(100, 0)}}}},
+	}})
+
+	seen := wb.lastModified(1)
+	if wb.changed(1, &seen) {
+		t.Errorf("unmodified thread reported as changed\n")
+	}
+
+	wb.update(&api.ThreadList{Pages: []api.ThreadListPage{
+		{Page: 1, Threads: []api.ThreadSummary{{No: 1, LastModified: api.Timestamp{Time: time.Unix(200, 0)}}}},
+	}})
+	if !wb.changed(1, &seen) || seen.Unix() != 200 {
+		t.Errorf("modified thread not reported as changed, seen: %d\n", seen.Unix())
+	}
+	if wb.changed(1, &seen) {
+		t.Errorf("thread reported as changed twice\n")
+	}
+
+	// Threads missing from threads.json are always requested
+	if !wb.changed(2, &seen) || !wb.changed(2, &seen) {
+		t.Errorf("missing thread not reported as changed\n")
+	}
+}
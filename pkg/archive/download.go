@@ -0,0 +1,215 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fiwippi/crow/pkg/api"
+)
+
+// Queues a media file to be saved to a specified output directory
+func (a *threadArchiver) queueFile(m *api.Media, dir string, count, total int, item string) {
+	a.queueKeyedFile(m, dir, "", count, total, item)
+}
+
+// Queues a media file to be saved to a specified output directory, the key
+// is marked as downloaded once the file is saved so it's retried if saving fails
+func (a *threadArchiver) queueKeyedFile(m *api.Media, dir, key string, count, total int, item string) {
+	a.emit(Event{Type: FileQueued, Kind: item, Path: dir + m.ID + m.Ext, URL: m.URL, Bytes: m.Size, Count: count, Total: total})
+	a.wg.Add(1)
+	go a.saveFile(m, dir, key, count, total, item)
+}
+
+// Saves a media file to a specified output directory
+func (a *threadArchiver) saveFile(m *api.Media, dir, key string, count, total int, item string) {
+	defer m.Body.Close()
+	defer a.wg.Done()
+
+	path := dir + m.ID + m.Ext
+	fail := func(err error) {
+		atomic.AddInt64(&a.failed, 1)
+		a.emit(Event{Type: FileFailed, Kind: item, Path: path, URL: m.URL, Count: count, Total: total, Err: err})
+	}
+
+	// Info
+	if item != "" {
+		item = " " + item
+	}
+	if total != 0 {
+		a.log.Debug().Str("file", path).Msg(fmt.Sprintf("saving%s... [%d/%d]", item, count, total))
+	} else {
+		a.log.Debug().Str("file", path).Msg(fmt.Sprintf("saving%s...", item))
+	}
+	item = strings.TrimPrefix(item, " ")
+
+	// Ensure the directory exists
+	x := strings.Split(path, "/")
+	fullDir := strings.Join(x[:len(x)-1], "/")
+	if _, err := os.Stat(fullDir); os.IsNotExist(err) {
+		err := os.MkdirAll(fullDir, os.ModePerm)
+		if err != nil {
+			a.log.Error().Err(err).Str("dir", fullDir).Msg("failed to create directory")
+			fail(err)
+			return
+		}
+	}
+
+	// Copy the contents to the file
+	var n int64
+	err := writeAtomic(path, func(w io.Writer) error {
+		var err error
+		n, err = io.Copy(w, m.Body)
+		return err
+	})
+	if err != nil {
+		a.log.Error().Err(err).Str("file", m.ID+m.Ext).Msg("failed to write file")
+		fail(err)
+		return
+	}
+
+	if key != "" {
+		a.visit(key)
+	}
+	atomic.AddInt64(&a.saved, n)
+	a.emit(Event{Type: FileSaved, Kind: item, Path: path, URL: m.URL, Bytes: n, Count: count, Total: total})
+}
+
+// Downloads all files and thumbnails from a thread
+func (a *threadArchiver) dlThreadFiles(t *api.Thread) {
+	defer a.wg.Done()
+
+	// Get the posts with files which match the filter and haven't been downloaded yet
+	posts := make([]*api.Post, 0)
+	for _, p := range t.Posts {
+		if !p.HasFile || a.seen(postKey(p)) {
+			continue
+		}
+		if !a.filter.Match(p) {
+			// Filtered files are only reported the first time they're seen
+			a.visit(postKey(p))
+			a.emit(Event{Type: FileSkipped, Kind: "images", Path: a.imgDir + p.ImageID.String() + p.Ext, URL: p.FileURL(a.c.SSL)})
+			continue
+		}
+		posts = append(posts, p)
+	}
+	total := len(posts)
+	if !a.filesOnly {
+		total *= 2 // Multiply by 2 because thumbnails
+	}
+
+	// Download files
+	count := 1
+	for _, p := range posts {
+		// Stop queueing files once archiving is cancelled, the queued files are still saved
+		if a.cancelled(nil) {
+			a.log.Debug().Int("no", t.No).Str("board", t.Board).Msg("archiving cancelled, not downloading remaining files")
+			return
+		}
+
+		// Always download thumbnails, cannot verify MD5 of thumbnail so always save
+		if !a.filesOnly {
+			m, err := a.c.GetThumbnail(p)
+			if a.cancelled(err) {
+				return
+			} else if err != nil {
+				a.log.Error().Err(err).Str("file", p.Filename+"s.jpg").Msg("failed to download file thumbnail")
+				a.failFile("thumbs", a.thumbDir+p.ImageID.String()+"s.jpg", count, total, err)
+			} else {
+				a.queueFile(m, a.thumbDir, count, total, "thumbs")
+			}
+			count += 1
+		}
+
+		// Download images if they dont exist or if overwriting true
+		if !a.overwrite && fileExists(a.imgDir+p.ImageID.String()+p.Ext) {
+			a.log.Debug().Str("file", a.imgDir+p.ImageID.String()+p.Ext).Msg("file already exists, not overwriting")
+			a.emit(Event{Type: FileSkipped, Kind: "images", Path: a.imgDir + p.ImageID.String() + p.Ext, Count: count, Total: total})
+			a.visit(postKey(p))
+			count += 1
+			continue
+		}
+		m, err := a.c.GetFile(p)
+		if a.cancelled(err) {
+			return
+		} else if err != nil {
+			a.log.Error().Err(err).Str("file", p.ImageID.String()+p.Ext).Msg("failed to download file")
+			a.failFile("images", a.imgDir+p.ImageID.String()+p.Ext, count, total, err)
+			count += 1
+			continue
+		}
+
+		// Ensure it has a valid MD5 Base64 encoded hash
+		if a.md5 && !api.VerifyMD5(p, m) {
+			a.log.Error().Str("file", p.ImageID.String()+p.Ext).Msg("MD5 hash of download does not match api supplied MD5, retrying...")
+			a.emit(Event{
+				Type:  MD5Mismatch,
+				Kind:  "images",
+				Path:  a.imgDir + p.ImageID.String() + p.Ext,
+				URL:   m.URL,
+				Bytes: m.Size,
+				Count: count,
+				Total: total,
+				Err:   fmt.Errorf("expected md5 %s but got %s", p.MD5, m.MD5),
+			})
+			m, err = a.c.GetFile(p)
+			if a.cancelled(err) {
+				return
+			} else if err == nil && !api.VerifyMD5(p, m) {
+				err = fmt.Errorf("expected md5 %s but got %s", p.MD5, m.MD5)
+			}
+			if err != nil {
+				a.log.Error().Err(err).Str("file", p.ImageID.String()+p.Ext).Msg("retry download failed")
+				a.failFile("images", a.imgDir+p.ImageID.String()+p.Ext, count, total, err)
+				count += 1
+				continue
+			}
+		}
+
+		// Download the file
+		a.queueKeyedFile(m, a.imgDir, postKey(p), count, total, "images")
+		count += 1
+	}
+}
+
+// Key used to mark a post's files as downloaded
+func postKey(p *api.Post) string {
+	return "post/" + strconv.Itoa(p.No)
+}
+
+// Records a file which could not be downloaded
+func (a *threadArchiver) failFile(item, path string, count, total int, err error) {
+	atomic.AddInt64(&a.failed, 1)
+	a.emit(Event{Type: FileFailed, Kind: item, Path: path, Count: count, Total: total, Err: err})
+}
+
+// Writes a file by writing to a temporary file which then replaces the file at
+// the path, so if crow is stopped while writing the file is never partially written
+func writeAtomic(path string, write func(w io.Writer) error) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Determines whether a file exists on the filesystem with the path
+func fileExists(path string) bool {
+	if _, err := os.Stat(path); err == nil {
+		return true
+	}
+	return false
+}
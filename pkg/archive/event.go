@@ -0,0 +1,114 @@
+package archive
+
+import "time"
+
+// EventType identifies what happened during archiving
+type EventType int
+
+const (
+	FileQueued     EventType = iota // A file has been downloaded and is queued to be written to disk
+	FileSaved                       // A file has been written to disk
+	FileFailed                      // A file could not be downloaded or written to disk
+	MD5Mismatch                     // The MD5 hash of a downloaded file did not match the one supplied by the api
+	AssetRewritten                  // A link in the thread's HTML or CSS was rewritten to point to a local file
+	ArchiveDone                     // The thread has finished archiving
+	StateChanged                    // The state of a watched thread has changed
+	FileSkipped                     // A file wasn't downloaded since it already exists or doesn't match the filter
+	ArchiveFailed                   // The thread couldn't be fetched or archived
+)
+
+func (t EventType) String() string {
+	switch t {
+	case FileQueued:
+		return "file_queued"
+	case FileSaved:
+		return "file_saved"
+	case FileFailed:
+		return "file_failed"
+	case MD5Mismatch:
+		return "md5_mismatch"
+	case AssetRewritten:
+		return "asset_rewritten"
+	case ArchiveDone:
+		return "archive_done"
+	case StateChanged:
+		return "state_changed"
+	case FileSkipped:
+		return "file_skipped"
+	case ArchiveFailed:
+		return "archive_failed"
+	default:
+		return "unknown"
+	}
+}
+
+// State is the state of a thread being watched
+type State int
+
+const (
+	StateWatching State = iota // The thread is being checked for updates
+	StateNotFound              // The thread 404'd
+	StateArchived              // The thread was moved to the board's archive
+	StateStale                 // The thread hasn't been modified in 72 hours
+	StateStopped               // The thread was archived once and isn't checked for updates
+)
+
+func (s State) String() string {
+	switch s {
+	case StateWatching:
+		return "watching"
+	case StateNotFound:
+		return "not_found"
+	case StateArchived:
+		return "archived"
+	case StateStale:
+		return "stale"
+	case StateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a single step of archiving a thread
+type Event struct {
+	Type   EventType
+	Board  string // Board of the thread being archived
+	No     int    // OP ID of the thread being archived
+	Kind   string // The kind of file, e.g. "images", "thumbs", "css", "script" or "assets"
+	Path   string // Local path of the file, for AssetRewritten this is the new link
+	URL    string // Remote URL of the file, for AssetRewritten this is the old link
+	Bytes  int64  // Size of the file in bytes, for ArchiveDone this is the total bytes saved
+	Count  int    // Position of the file in the thread's file list, zero if not applicable
+	Total  int    // Number of files in the thread's file list, zero if not applicable
+	Err    error  // The error which occurred for FileFailed, MD5Mismatch and ArchiveFailed events
+	Failed int    // Number of files which failed to save, only set for ArchiveDone
+	State  State  // The new state of the thread, only set for StateChanged
+
+	Duration time.Duration // Time taken to archive the thread, only set for ArchiveDone
+}
+
+// Observer receives events from the archiver, it may be called from
+// multiple goroutines at once so implementations should be safe for
+// concurrent use
+type Observer interface {
+	Observe(e Event)
+}
+
+// ObserverFunc allows an ordinary function to be used as an Observer
+type ObserverFunc func(e Event)
+
+func (f ObserverFunc) Observe(e Event) {
+	f(e)
+}
+
+// Observers sends each event to every observer in order, nil observers are skipped
+type Observers []Observer
+
+func (o Observers) Observe(e Event) {
+	for _, obs := range o {
+		if obs != nil {
+			obs.Observe(e)
+		}
+	}
+}
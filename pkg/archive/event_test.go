@@ -0,0 +1,250 @@
+package archive
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fiwippi/crow/pkg/api"
+)
+
+// transportFunc serves requests without using the network, requests
+// whose context is done once they're served return the context's error
+type transportFunc func(r *http.Request) *http.Response
+
+func (f transportFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp := f(r)
+	if err := r.Context().Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// swapTransport serves the requests of clients without a transport of their own using f
+func swapTransport(t *testing.T, f transportFunc) {
+	dt := http.DefaultTransport
+	t.Cleanup(func() { http.DefaultTransport = dt })
+	http.DefaultTransport = f
+}
+
+// fakeTransport serves the requests of clients without a transport
+// of their own by the routes, keyed by the URL, unknown URLs return a 404
+func fakeTransport(t *testing.T, routes map[string]string) {
+	swapTransport(t, func(r *http.Request) *http.Response {
+		return route(r, routes)
+	})
+}
+
+// route returns the response to the request from the routes, keyed by the URL
+func route(r *http.Request, routes map[string]string) *http.Response {
+	body, found := routes[r.URL.String()]
+	status := http.StatusOK
+	if !found {
+		status = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: status,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Header:     make(http.Header),
+		Request:    r,
+	}
+}
+
+// recorder is an Observer which records the events it receives
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) Observe(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+// take returns the events recorded since it was last called
+func (r *recorder) take() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+// byPath groups the types of the file events by the file's path
+func byPath(events []Event) map[string][]EventType {
+	types := make(map[string][]EventType)
+	for _, e := range events {
+		if e.Path != "" {
+			types[e.Path] = append(types[e.Path], e.Type)
+		}
+	}
+	return types
+}
+
+func TestArchiveEvents(t *testing.T) {
+	fakeTransport(t, map[string]string{
+		"https://a.4cdn.org/boards.json": `{"boards": [{"board": "po"}]}`,
+		"https://i.4cdn.org/po/1000.png": "image",
+		"https://i.4cdn.org/po/3000.png": "image",
+		"https://boards.4chan.org/po/thread/1": `<html><head>` +
+			`<link rel="stylesheet" href="//s.4cdn.org/css/yotsubluemobile.css"></head><body></body></html>`,
+		"https://s.4cdn.org/css/yotsubluemobile.css": `body { background: url(//s.4cdn.org/image/fade.png) repeat-x; color: #000; margin: 0; }`,
+		"https://s.4cdn.org/image/fade.png":          "fade",
+	})
+
+	hash := md5.Sum([]byte("image"))
+	post := func(no int, id string) *api.Post {
+		return &api.Post{
+			Board:    "po",
+			No:       no,
+			HasFile:  true,
+			ImageID:  json.Number(id),
+			Ext:      ".png",
+			Filesize: 5,
+			MD5:      base64.StdEncoding.EncodeToString(hash[:]),
+		}
+	}
+	thread := &api.Thread{Board: "po", No: 1, Posts: []*api.Post{post(1, "1000"), post(2, "2000"), post(3, "3000")}}
+
+	rec := &recorder{}
+	a := New(api.NewClient(1000, 1000, true, false), Options{
+		Dst:         t.TempDir(),
+		FilesOnly:   true,
+		ValidateMD5: true,
+		Observer:    rec,
+	})
+
+	// Post 3's file can't be written since a directory is in the way
+	dir := a.Dir("po", 1) + "images/"
+	if err := os.MkdirAll(dir+"3000.png.tmp", os.ModePerm); err != nil {
+		t.Fatalf("failed to create dir: %s\n", err)
+	}
+	if err := a.Archive(thread); err != nil {
+		t.Fatalf("failed to archive thread: %s\n", err)
+	}
+
+	// Post 1's file is queued before it's saved, post 2's file 404s, post 3's
+	// file fails to save and the thread is only done once every file is handled
+	events := rec.take()
+	for _, e := range events {
+		if e.Type == FileSaved && e.Bytes != 5 {
+			t.Errorf("wrong bytes saved, expected 5 but got: %d\n", e.Bytes)
+		}
+	}
+	types := byPath(events[:len(events)-1])
+	if got := types[dir+"1000.png"]; len(got) != 2 || got[0] != FileQueued || got[1] != FileSaved {
+		t.Errorf("wrong events for saved file: %v\n", got)
+	}
+	if got := types[dir+"2000.png"]; len(got) != 1 || got[0] != FileFailed {
+		t.Errorf("wrong events for missing file: %v\n", got)
+	}
+	if got := types[dir+"3000.png"]; len(got) != 2 || got[0] != FileQueued || got[1] != FileFailed {
+		t.Errorf("wrong events for unwritable file: %v\n", got)
+	}
+	done := events[len(events)-1]
+	if done.Type != ArchiveDone || done.Bytes != 5 || done.Failed != 2 || done.Board != "po" || done.No != 1 {
+		t.Errorf("wrong final event: %+v\n", done)
+	}
+
+	// Saved files aren't downloaded again but failed files are retried
+	os.Remove(dir + "3000.png.tmp")
+	if err := a.Archive(thread); err != nil {
+		t.Fatalf("failed to archive thread: %s\n", err)
+	}
+	events = rec.take()
+	types = byPath(events)
+	if _, found := types[dir+"1000.png"]; found || len(types[dir+"2000.png"]) != 1 {
+		t.Errorf("wrong events when archiving again: %v\n", types)
+	}
+	if got := types[dir+"3000.png"]; len(got) != 2 || got[1] != FileSaved {
+		t.Errorf("unwritable file not retried: %v\n", got)
+	}
+	if done := events[len(events)-1]; done.Type != ArchiveDone || done.Bytes != 5 || done.Failed != 1 {
+		t.Errorf("wrong final event when archiving again: %+v\n", done)
+	}
+
+	// Links in the thread's HTML and CSS are rewritten to the local paths
+	h := New(api.NewClient(1000, 1000, true, false), Options{Dst: t.TempDir(), Format: FormatHTML, Observer: rec})
+	if err := h.Archive(&api.Thread{Board: "po", No: 1, Posts: []*api.Post{{Board: "po", No: 1}}}); err != nil {
+		t.Fatalf("failed to archive thread html: %s\n", err)
+	}
+	rewritten := make(map[string]Event)
+	for _, e := range rec.take() {
+		if e.Type == AssetRewritten {
+			rewritten[e.URL] = e
+		}
+	}
+	expected := []Event{
+		{Kind: "css", Path: "css/yotsubluemobile.css", URL: "//s.4cdn.org/css/yotsubluemobile.css"},
+		{Kind: "css", Path: "assets/fade.png", URL: "/s.4cdn.org/image/fade.png"},
+	}
+	for _, e := range expected {
+		if got := rewritten[e.URL]; got.Kind != e.Kind || got.Path != e.Path {
+			t.Errorf("wrong rewrite of %s, expected kind %s and path %s but got: %+v\n", e.URL, e.Kind, e.Path, got)
+		}
+	}
+
+	css, err := os.ReadFile(h.Dir("po", 1) + "css/yotsubluemobile.css")
+	if err != nil || !strings.Contains(string(css), `url("assets/fade.png")`) {
+		t.Errorf("css links not rewritten, got: %s, err: %v\n", css, err)
+	}
+}
+
+func TestArchiveCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Archiving is cancelled while post 2's file is being downloaded
+	routes := map[string]string{
+		"https://a.4cdn.org/boards.json": `{"boards": [{"board": "po"}]}`,
+		"https://i.4cdn.org/po/1000.png": "image",
+		"https://i.4cdn.org/po/2000.png": "image",
+		"https://i.4cdn.org/po/3000.png": "image",
+	}
+	var requested int64
+	swapTransport(t, func(r *http.Request) *http.Response {
+		atomic.AddInt64(&requested, 1)
+		if r.URL.Path == "/po/2000.png" {
+			cancel()
+		}
+		return route(r, routes)
+	})
+
+	posts := make([]*api.Post, 3)
+	for i := range posts {
+		posts[i] = &api.Post{Board: "po", No: i + 1, HasFile: true, ImageID: json.Number(fmt.Sprint(i+1) + "000"), Ext: ".png"}
+	}
+	rec, summary := &recorder{}, NewSummary()
+	a := New(api.NewClient(1000, 1000, true, false), Options{
+		Dst:       t.TempDir(),
+		FilesOnly: true,
+		Observer:  Observers{rec, summary},
+	})
+	if err := a.ArchiveContext(ctx, &api.Thread{Board: "po", No: 1, Posts: posts}); err != nil {
+		t.Fatalf("failed to archive thread: %s\n", err)
+	}
+
+	// The file downloaded before archiving was cancelled is
+	// saved and the files after it aren't reported as failed
+	for _, e := range rec.take() {
+		if e.Type == FileFailed || e.Type == ArchiveFailed {
+			t.Errorf("failure reported after archiving was cancelled: %+v\n", e)
+		}
+	}
+	if totals := summary.Totals(); totals.Failed() || totals.FilesSaved != 1 {
+		t.Errorf("wrong totals after archiving was cancelled: %+v\n", totals)
+	}
+	if n := atomic.LoadInt64(&requested); n != 2 {
+		t.Errorf("expected requests for two files but got %d\n", n)
+	}
+}
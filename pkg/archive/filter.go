@@ -0,0 +1,49 @@
+package archive
+
+import (
+	"strings"
+
+	"github.com/fiwippi/crow/pkg/api"
+)
+
+// Filter decides which files of a thread are downloaded, files which don't
+// match are left as links to 4chan in the thread's HTML. The zero value
+// matches every file
+type Filter struct {
+	Extensions  []string // Extensions of files to download, e.g. ".webm" or "gif", every extension if empty
+	MinFilesize int      // Minimum size of files to download in bytes, no minimum if zero
+	MaxFilesize int      // Maximum size of files to download in bytes, no maximum if zero
+	MinWidth    int      // Minimum width of files to download in pixels, no minimum if zero
+	MinHeight   int      // Minimum height of files to download in pixels, no minimum if zero
+	NoSpoilers  bool     // Whether to skip spoilered files
+	OPOnly      bool     // Whether to only download the file of the thread's OP
+}
+
+// Match returns whether the post's file should be downloaded, posts
+// without files never match
+func (f Filter) Match(p *api.Post) bool {
+	switch {
+	case !p.HasFile:
+		return false
+	case f.OPOnly && p.RepliesTo != 0:
+		return false
+	case f.NoSpoilers && bool(p.ImageSpoiler):
+		return false
+	case p.Filesize < f.MinFilesize:
+		return false
+	case f.MaxFilesize > 0 && p.Filesize > f.MaxFilesize:
+		return false
+	case p.ImageWidth < f.MinWidth || p.ImageHeight < f.MinHeight:
+		return false
+	}
+
+	if len(f.Extensions) == 0 {
+		return true
+	}
+	for _, ext := range f.Extensions {
+		if strings.EqualFold("."+strings.TrimPrefix(ext, "."), p.Ext) {
+			return true
+		}
+	}
+	return false
+}
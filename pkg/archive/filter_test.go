@@ -0,0 +1,36 @@
+package archive
+
+import (
+	"testing"
+
+	"github.com/fiwippi/crow/pkg/api"
+)
+
+func TestFilterMatch(t *testing.T) {
+	op := &api.Post{No: 1, HasFile: true, Ext: ".webm", Filesize: 2000, ImageWidth: 1280, ImageHeight: 720}
+	reply := &api.Post{No: 2, RepliesTo: 1, HasFile: true, Ext: ".gif", Filesize: 500, ImageWidth: 200, ImageHeight: 200}
+	spoiler := &api.Post{No: 3, RepliesTo: 1, HasFile: true, Ext: ".jpg", Filesize: 1000, ImageWidth: 800, ImageHeight: 600, ImageSpoiler: true}
+	text := &api.Post{No: 4, RepliesTo: 1}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []bool // Whether op, reply, spoiler and text match
+	}{
+		{"zero value", Filter{}, []bool{true, true, true, false}},
+		{"extensions", Filter{Extensions: []string{"WEBM", ".gif"}}, []bool{true, true, false, false}},
+		{"min filesize", Filter{MinFilesize: 1000}, []bool{true, false, true, false}},
+		{"max filesize", Filter{MaxFilesize: 1000}, []bool{false, true, true, false}},
+		{"min dimensions", Filter{MinWidth: 800, MinHeight: 700}, []bool{true, false, false, false}},
+		{"no spoilers", Filter{NoSpoilers: true}, []bool{true, true, false, false}},
+		{"op only", Filter{OPOnly: true}, []bool{true, false, false, false}},
+	}
+
+	for _, tc := range tests {
+		for i, p := range []*api.Post{op, reply, spoiler, text} {
+			if got := tc.filter.Match(p); got != tc.want[i] {
+				t.Errorf("%s: post %d matched: %v but expected: %v\n", tc.name, p.No, got, tc.want[i])
+			}
+		}
+	}
+}
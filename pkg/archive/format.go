@@ -1,4 +1,4 @@
-package archiver
+package archive
 
 import (
 	"io"
@@ -6,7 +6,6 @@ import (
 
 	"golang.org/x/net/html"
 
-	"github.com/fiwippi/crow/internal/log"
 	"github.com/fiwippi/crow/pkg/api"
 )
 
@@ -29,43 +28,40 @@ func removeUnwanted(n *html.Node) {
 }
 
 // Downloads assets and redirects assets and images to local counterparts
-func redirect(n *html.Node, a *archiver, t *api.Thread) {
+func redirect(n *html.Node, a *threadArchiver, links map[string]string) {
 	switch n.Data {
 	case "a":
-		redirectA(n, t)
+		redirectA(n, a, links)
 	case "link":
 		redirectLink(n, a)
 	case "script":
 		redirectScript(n, a)
 	case "img":
-		redirectImage(n, a, t)
+		redirectImage(n, a, links)
 	case "div":
 		redirectDiv(n, a)
 	}
 
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		redirect(c, a, t)
+		redirect(c, a, links)
 	}
 }
 
 // Formats the downloaded HTML page to redirect links to static assets and remove
 // unwanted javascript which loads ads
-func (a *archiver) formatHTML(data io.Reader, errChan chan error, htmlChan chan *html.Node, t *api.Thread) {
-	log.Info().Int("no", t.No).Str("board", t.Board).Msg("formatting HTML data")
-	defer a.wg.Done()
+func (a *threadArchiver) formatHTML(data io.Reader, t *api.Thread) (*html.Node, error) {
+	a.log.Info().Int("no", t.No).Str("board", t.Board).Msg("formatting HTML data")
 
 	// Parse the html data into a doc
 	doc, err := html.Parse(data)
 	if err != nil {
-		errChan <- err
+		return nil, err
 	}
 
 	// Downloads all assets and removes unwanted html elements in the page
-	redirect(doc, a, t)
+	redirect(doc, a, mediaLinks(t, a.c.SSL, a.filter))
 	removeUnwanted(doc)
 
-	errChan <- nil
-	htmlChan <- doc
-
-	log.Info().Int("no", t.No).Str("board", t.Board).Msg("done formatting HTML data")
+	a.log.Info().Int("no", t.No).Str("board", t.Board).Msg("done formatting HTML data")
+	return doc, nil
 }
@@ -1,4 +1,4 @@
-package archiver
+package archive
 
 import (
 	"bytes"
@@ -6,7 +6,6 @@ import (
 	"io"
 	"os"
 
-	"github.com/fiwippi/crow/internal/log"
 	"github.com/fiwippi/crow/pkg/api"
 )
 
@@ -41,35 +40,35 @@ var iconRefresh []byte
 var iconReport []byte
 
 // Saves all the embedded icons to the assets dir
-func (a *archiver) saveIcons(t *api.Thread) {
+func (a *threadArchiver) saveIcons(t *api.Thread) {
 	defer a.wg.Done()
 
 	path := a.assetDir + "image/buttons/burichan/"
 	err := os.MkdirAll(path, os.ModePerm)
 	if err != nil {
-		log.Error().Err(err).Str("dir", "image/buttons/burichan/").Msg("failed to create icons dir")
+		a.log.Error().Err(err).Str("dir", "image/buttons/burichan/").Msg("failed to create icons dir")
 		return
 	}
 
-	saveIcon(path+"arrow_down.png", iconArrowDown)
-	saveIcon(path+"arrow_down2.png", iconArrowDown2)
-	saveIcon(path+"arrow_right.png", iconArrowRight)
-	saveIcon(path+"arrow_up.png", iconArrowUp)
-	saveIcon(path+"cross.png", iconCross)
-	saveIcon(path+"post_expand_minus.png", iconPostExpandMinus)
-	saveIcon(path+"post_expand_plus.png", iconPostExpandPlus)
-	saveIcon(path+"post_expand_rotate.gif", iconPostExpandRotate)
-	saveIcon(path+"refresh.png", iconRefresh)
-	saveIcon(path+"report.png", iconReport)
-
-	log.Info().Int("no", t.No).Str("board", t.Board).Msg("finished saving icons")
+	a.saveIcon(path+"arrow_down.png", iconArrowDown)
+	a.saveIcon(path+"arrow_down2.png", iconArrowDown2)
+	a.saveIcon(path+"arrow_right.png", iconArrowRight)
+	a.saveIcon(path+"arrow_up.png", iconArrowUp)
+	a.saveIcon(path+"cross.png", iconCross)
+	a.saveIcon(path+"post_expand_minus.png", iconPostExpandMinus)
+	a.saveIcon(path+"post_expand_plus.png", iconPostExpandPlus)
+	a.saveIcon(path+"post_expand_rotate.gif", iconPostExpandRotate)
+	a.saveIcon(path+"refresh.png", iconRefresh)
+	a.saveIcon(path+"report.png", iconReport)
+
+	a.log.Info().Int("no", t.No).Str("board", t.Board).Msg("finished saving icons")
 }
 
-func saveIcon(path string, data []byte) {
+func (a *threadArchiver) saveIcon(path string, data []byte) {
 	// Create the file on the host
 	out, err := os.Create(path)
 	if err != nil {
-		log.Error().Err(err).Str("file", path).Msg("failed to create icon")
+		a.log.Error().Err(err).Str("file", path).Msg("failed to create icon")
 		return
 	}
 	defer out.Close()
@@ -77,7 +76,7 @@ func saveIcon(path string, data []byte) {
 	// Copy the contents to the file
 	_, err = io.Copy(out, bytes.NewReader(data))
 	if err != nil {
-		log.Error().Err(err).Str("file", path).Msg("failed to write icon")
+		a.log.Error().Err(err).Str("file", path).Msg("failed to write icon")
 		return
 	}
 }
@@ -1,41 +1,42 @@
-package archiver
+package archive
 
 import (
 	"bytes"
 	"io"
 	"io/ioutil"
+	"net/url"
 	"regexp"
 	"strings"
 
 	"golang.org/x/net/html"
 
-	"github.com/fiwippi/crow/internal/log"
 	"github.com/fiwippi/crow/pkg/api"
 )
 
-func redirectA(n *html.Node, t *api.Thread) {
+func redirectA(n *html.Node, a *threadArchiver, links map[string]string) {
 	for i, v := range n.Attr {
-		for _, domain := range []string{api.MediaDomainA, api.MediaDomainB} {
-			if v.Key == "href" && strings.Contains(v.Val, domain) {
-				endpoint := strings.TrimPrefix(v.Val, "//"+domain+"/"+t.Board+"/")
-				if strings.Contains(endpoint, "s") {
-					n.Attr[i].Val = "thumbs/" + endpoint
-				} else {
-					n.Attr[i].Val = "images/" + endpoint
-				}
+		if v.Key != "href" {
+			continue
+		}
+		if path, ok := mediaPath(v.Val); ok {
+			if local, found := links[path]; found {
+				a.rewrite(n, i, mediaKind(local), local)
 			}
 		}
 	}
 }
 
-func redirectLink(n *html.Node, a *archiver) {
+func redirectLink(n *html.Node, a *threadArchiver) {
 	for i, v := range n.Attr {
 		if v.Key == "href" && strings.Contains(v.Val, api.StaticDomain) {
 			// Download the linked static asset
 			endpoint := strings.TrimPrefix(v.Val, "//"+api.StaticDomain+"/")
 			m, err := a.c.GetStaticAsset(endpoint)
-			if err != nil {
-				log.Error().Err(err).Str("file", endpoint).Msg("failed to download file")
+			if a.cancelled(err) {
+				return
+			} else if err != nil {
+				a.log.Error().Err(err).Str("file", endpoint).Msg("failed to download file")
+				a.failFile("assets", a.assetDir+endpoint, 0, 0, err)
 				continue
 			}
 
@@ -44,7 +45,7 @@ func redirectLink(n *html.Node, a *archiver) {
 				b, err := ioutil.ReadAll(m.Body)
 				m.Body.Close()
 				if err != nil {
-					log.Error().Err(err).Str("file", endpoint).Msg("failed to read css script body")
+					a.log.Error().Err(err).Str("file", endpoint).Msg("failed to read css script body")
 					continue
 				}
 
@@ -64,30 +65,31 @@ func redirectLink(n *html.Node, a *archiver) {
 						endpoint := strings.TrimPrefix(staticURL, "url(/")
 						oldEndpoints = append(oldEndpoints, endpoint)
 						endpoint = strings.TrimPrefix(endpoint, "/s.4cdn.org/")
-						newEndpoints = append(newEndpoints, "\""+strings.ReplaceAll(endpoint, "image", "assets")+"\"")
+						newEndpoints = append(newEndpoints, strings.ReplaceAll(endpoint, "image", "assets"))
 
-						_, found := a.downloaded[endpoint]
-						if !found {
-							a.downloaded[endpoint] = struct{}{}
+						if a.visit(endpoint) {
 							assetM, err := a.c.GetStaticAsset(endpoint)
-							if err != nil {
-								log.Error().Err(err).Str("file", endpoint).Msg("failed to download file")
+							if a.cancelled(err) {
+								return
+							} else if err != nil {
+								a.log.Error().Err(err).Str("file", endpoint).Msg("failed to download file")
+								a.failFile("assets", a.assetDir+endpoint, 0, 0, err)
 								continue
 							}
 
-							a.wg.Add(1)
-							go a.saveFile(assetM, a.assetDir, 0, 0, "assets")
+							a.queueFile(assetM, a.assetDir, 0, 0, "assets")
 						}
 					}
 				}
 
 				for i, str := range oldEndpoints {
+					a.emit(Event{Type: AssetRewritten, Kind: "css", Path: newEndpoints[i], URL: str})
 					if strings.HasPrefix(str, "/") { // Add the extra "/" for changing "//s.4cdn..."
 						str = "/" + str
 					}
 					regexStr = regexp.QuoteMeta(str)
 					urlRegex := regexp.MustCompile(regexStr)
-					scriptStr = string(urlRegex.ReplaceAll([]byte(scriptStr), []byte(newEndpoints[i])))
+					scriptStr = string(urlRegex.ReplaceAll([]byte(scriptStr), []byte("\""+newEndpoints[i]+"\"")))
 				}
 				urlRegex = regexp.MustCompile(`/"assets/`)
 				scriptStr = string(urlRegex.ReplaceAll([]byte(scriptStr), []byte(`"assets/`)))
@@ -95,73 +97,73 @@ func redirectLink(n *html.Node, a *archiver) {
 				// Feed the new media to the save function
 				reader := io.NopCloser(strings.NewReader(scriptStr))
 				m.Body = reader
+				m.Size = int64(len(scriptStr))
 
-				a.wg.Add(1)
-				go a.saveFile(m, a.cssDir, 0, 0, "css")
+				a.queueFile(m, a.cssDir, 0, 0, "css")
 			} else {
-				a.wg.Add(1)
-				go a.saveFile(m, a.assetDir, 0, 0, "assets")
+				a.queueFile(m, a.assetDir, 0, 0, "assets")
 			}
 
 			// Reflect the change in the HTML document
-			n.Attr[i].Val = strings.ReplaceAll(endpoint, "image", "assets")
+			kind := "assets"
+			if strings.HasPrefix(endpoint, "css") {
+				kind = "css"
+			}
+			a.rewrite(n, i, kind, strings.ReplaceAll(endpoint, "image", "assets"))
 		}
 	}
 }
 
-func redirectImage(n *html.Node, a *archiver, t *api.Thread) {
+func redirectImage(n *html.Node, a *threadArchiver, links map[string]string) {
 	for i, v := range n.Attr {
 		if v.Key == "src" {
 			// If the image is media then it's already being downloaded in dlThreadFiles so only redirect url
-			if strings.Contains(v.Val, api.MediaDomainA) {
-				endpoint := strings.TrimPrefix(v.Val, "//"+api.MediaDomainA+"/"+t.Board+"/")
-				if strings.Contains(endpoint, "s") {
-					n.Attr[i].Val = "thumbs/" + endpoint
-				} else {
-					n.Attr[i].Val = "images/" + endpoint
+			if path, ok := mediaPath(v.Val); ok {
+				if local, found := links[path]; found {
+					a.rewrite(n, i, mediaKind(local), local)
 				}
 			}
 
 			// If it's a static asset then download it
 			if strings.Contains(v.Val, api.StaticDomain) {
 				endpoint := strings.TrimPrefix(v.Val, "//"+api.StaticDomain+"/")
-				_, found := a.downloaded[endpoint]
-				if !found {
-					a.downloaded[endpoint] = struct{}{}
+				if a.visit(endpoint) {
 					m, err := a.c.GetStaticAsset(endpoint)
-					if err != nil {
-						log.Error().Err(err).Str("file", endpoint).Msg("failed to download file")
+					if a.cancelled(err) {
+						return
+					} else if err != nil {
+						a.log.Error().Err(err).Str("file", endpoint).Msg("failed to download file")
+						a.failFile("assets", a.assetDir+endpoint, 0, 0, err)
 						continue
 					}
 
-					a.wg.Add(1)
-					go a.saveFile(m, a.assetDir, 0, 0, "assets")
+					a.queueFile(m, a.assetDir, 0, 0, "assets")
 				}
 
 				// Remove all slashes in the endpoint to get to the filename
 				link := strings.ReplaceAll(endpoint, "image", "assets")
-				n.Attr[i].Val = link
+				a.rewrite(n, i, "assets", link)
 			}
 		}
 	}
 }
 
-func redirectDiv(n *html.Node, a *archiver) {
+func redirectDiv(n *html.Node, a *threadArchiver) {
 	for _, v := range n.Attr {
 		// Downloads the title banner
 		if v.Key == "data-src" {
 			endpoint := "/image/title/" + v.Val
-			_, found := a.downloaded[endpoint]
-			if !found {
-				a.downloaded[endpoint] = struct{}{}
+			if a.visit(endpoint) {
 				m, err := a.c.GetStaticAsset(endpoint)
-				if err != nil {
-					log.Error().Err(err).Str("file", endpoint).Msg("failed to download file")
+				if a.cancelled(err) {
+					return
+				} else if err != nil {
+					a.log.Error().Err(err).Str("file", endpoint).Msg("failed to download file")
+					a.failFile("assets", a.assetDir+endpoint, 0, 0, err)
 					continue
 				}
 
-				a.wg.Add(1)
-				go a.saveFile(m, a.assetDir, 0, 0, "assets")
+				a.queueFile(m, a.assetDir, 0, 0, "assets")
 			}
 
 			// We add the banner image manually since the JS script does not add it in
@@ -169,15 +171,16 @@ func redirectDiv(n *html.Node, a *archiver) {
 				Type: html.ElementNode,
 				Data: "img",
 				Attr: []html.Attribute{
-					{"", "src", "assets" + endpoint},
+					{Key: "src", Val: "assets" + endpoint},
 				},
 			}
 			n.AppendChild(imgNode)
+			a.emit(Event{Type: AssetRewritten, Kind: "assets", Path: "assets" + endpoint, URL: v.Val})
 		}
 	}
 }
 
-func redirectScript(n *html.Node, a *archiver) {
+func redirectScript(n *html.Node, a *threadArchiver) {
 	for i, v := range n.Attr {
 		// Remove unwanted advertisement script
 		if v.Key == "src" && strings.Contains(v.Val, "bid.glass") {
@@ -189,8 +192,11 @@ func redirectScript(n *html.Node, a *archiver) {
 			// Download the scripts
 			endpoint := strings.TrimPrefix(v.Val, "//"+api.StaticDomain+"/")
 			m, err := a.c.GetStaticAsset(endpoint)
-			if err != nil {
-				log.Error().Err(err).Str("file", endpoint).Msg("failed to download file")
+			if a.cancelled(err) {
+				return
+			} else if err != nil {
+				a.log.Error().Err(err).Str("file", endpoint).Msg("failed to download file")
+				a.failFile("script", a.jsDir+endpoint, 0, 0, err)
 				continue
 			}
 
@@ -198,7 +204,7 @@ func redirectScript(n *html.Node, a *archiver) {
 			b, err := ioutil.ReadAll(m.Body)
 			m.Body.Close()
 			if err != nil {
-				log.Error().Err(err).Str("file", endpoint).Msg("failed to read js script body")
+				a.log.Error().Err(err).Str("file", endpoint).Msg("failed to read js script body")
 				continue
 			}
 
@@ -234,16 +240,59 @@ func redirectScript(n *html.Node, a *archiver) {
 			var reader io.ReadCloser
 			if scriptStr != "" {
 				reader = io.NopCloser(strings.NewReader(scriptStr))
+				m.Size = int64(len(scriptStr))
 			} else {
 				reader = io.NopCloser(bytes.NewReader(b))
 			}
 			m.Body = reader
 
-			a.wg.Add(1)
-			go a.saveFile(m, a.jsDir, 0, 0, "script")
+			a.queueFile(m, a.jsDir, 0, 0, "script")
 
 			// Change v.Val
-			n.Attr[i].Val = endpoint
+			a.rewrite(n, i, "script", endpoint)
+		}
+	}
+}
+
+// rewrite changes the value of the node's attribute at index i
+// and notifies the observer of the new link to the kind of file
+func (a *threadArchiver) rewrite(n *html.Node, i int, kind, val string) {
+	a.emit(Event{Type: AssetRewritten, Kind: kind, Path: val, URL: n.Attr[i].Val})
+	n.Attr[i].Val = val
+}
+
+// mediaKind returns the kind of a local link from mediaLinks, the
+// links are to files in the images or thumbs dir which is their kind
+func mediaKind(local string) string {
+	return strings.SplitN(local, "/", 2)[0]
+}
+
+// mediaLinks maps the path of every file and thumbnail in the thread to the
+// relative path it's saved to in the archive, files which don't match the
+// filter aren't downloaded so they keep linking to 4chan
+func mediaLinks(t *api.Thread, ssl bool, f Filter) map[string]string {
+	links := make(map[string]string)
+	for _, p := range t.Posts {
+		if !f.Match(p) {
+			continue
 		}
+		if path, ok := mediaPath(p.FileURL(ssl)); ok {
+			links[path] = "images/" + p.ImageID.String() + p.Ext
+		}
+		if path, ok := mediaPath(p.ThumbnailURL(ssl)); ok {
+			links[path] = "thumbs/" + p.ImageID.String() + "s.jpg"
+		}
+	}
+	return links
+}
+
+// mediaPath returns the path of the link if it's to one of the
+// media domains, links in the thread's HTML are protocol relative
+// and files may be served from either domain so only the path is used
+func mediaPath(link string) (string, bool) {
+	u, err := url.Parse(link)
+	if err != nil || (u.Host != api.MediaDomainA && u.Host != api.MediaDomainB) {
+		return "", false
 	}
+	return u.Path, true
 }
@@ -0,0 +1,52 @@
+package archive
+
+import (
+	"testing"
+
+	"github.com/fiwippi/crow/pkg/api"
+)
+
+func TestMediaLinks(t *testing.T) {
+	thread := &api.Thread{Board: "po", Posts: []*api.Post{
+		{Board: "po", No: 1, HasFile: true, ImageID: "1546293948883", Ext: ".swf"},
+		{Board: "po", No: 2},
+	}}
+	links := mediaLinks(thread, true, Filter{})
+
+	tests := []struct {
+		link, want string
+	}{
+		{"//i.4cdn.org/po/1546293948883.swf", "images/1546293948883.swf"},
+		{"https://is2.4chan.org/po/1546293948883.swf", "images/1546293948883.swf"},
+		{"//i.4cdn.org/po/1546293948883s.jpg", "thumbs/1546293948883s.jpg"},
+		{"//i.4cdn.org/po/123.jpg", ""},
+		{"//s.4cdn.org/image/spoiler.png", ""},
+	}
+
+	for _, tc := range tests {
+		path, _ := mediaPath(tc.link)
+		if got := links[path]; got != tc.want {
+			t.Errorf("link %s redirected to %q but expected %q\n", tc.link, got, tc.want)
+		}
+	}
+}
+
+func TestMediaLinksFiltered(t *testing.T) {
+	thread := &api.Thread{Board: "po", Posts: []*api.Post{
+		{Board: "po", No: 1, HasFile: true, ImageID: "1546293948883", Ext: ".swf"},
+		{Board: "po", No: 2, RepliesTo: 1, HasFile: true, ImageID: "1546293948884", Ext: ".png"},
+	}}
+
+	// Filtered files aren't downloaded so they keep linking to 4chan
+	links := mediaLinks(thread, true, Filter{Extensions: []string{"png"}})
+	for _, link := range []string{"//i.4cdn.org/po/1546293948883.swf", "//i.4cdn.org/po/1546293948883s.jpg"} {
+		path, _ := mediaPath(link)
+		if local, found := links[path]; found {
+			t.Errorf("filtered link %s redirected to %q\n", link, local)
+		}
+	}
+	path, _ := mediaPath("//i.4cdn.org/po/1546293948884.png")
+	if links[path] != "images/1546293948884.png" {
+		t.Errorf("unfiltered link not redirected, got: %q\n", links[path])
+	}
+}
@@ -0,0 +1,73 @@
+package archive
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Totals are the totals of what was archived
+type Totals struct {
+	Threads        int   `json:"threads"`         // Number of threads which were archived at least once
+	ThreadsFailed  int   `json:"threads_failed"`  // Number of threads which couldn't be fetched or archived at least once
+	FilesAttempted int   `json:"files_attempted"` // Number of files which were downloaded, this is FilesSaved plus FilesFailed
+	FilesSaved     int   `json:"files_saved"`     // Number of files written to disk
+	FilesSkipped   int   `json:"files_skipped"`   // Number of files which already existed or didn't match the filter
+	FilesFailed    int   `json:"files_failed"`    // Number of files which couldn't be downloaded or written to disk
+	MD5Mismatches  int   `json:"md5_mismatches"`  // Number of downloads whose MD5 hash didn't match the api's
+	Bytes          int64 `json:"bytes"`           // Total bytes written to disk
+}
+
+// Failed returns whether any thread or file failed
+func (t Totals) Failed() bool {
+	return t.ThreadsFailed > 0 || t.FilesFailed > 0
+}
+
+// Summary is an Observer which totals what the archivers it
+// observes have saved, e.g. to print once crow is stopped
+type Summary struct {
+	mu      sync.Mutex
+	totals  Totals
+	threads map[string]struct{} // Keyed by board/no
+	failed  map[string]struct{} // Keyed by board/no
+}
+
+// NewSummary creates an empty Summary
+func NewSummary() *Summary {
+	return &Summary{
+		threads: make(map[string]struct{}),
+		failed:  make(map[string]struct{}),
+	}
+}
+
+// Observe implements Observer
+func (s *Summary) Observe(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch e.Type {
+	case FileSaved:
+		s.totals.FilesSaved++
+		s.totals.Bytes += e.Bytes
+	case FileSkipped:
+		s.totals.FilesSkipped++
+	case FileFailed:
+		s.totals.FilesFailed++
+	case MD5Mismatch:
+		s.totals.MD5Mismatches++
+	case ArchiveDone:
+		s.threads[fmt.Sprintf("%s/%d", e.Board, e.No)] = struct{}{}
+		s.totals.Threads = len(s.threads)
+	case ArchiveFailed:
+		s.failed[fmt.Sprintf("%s/%d", e.Board, e.No)] = struct{}{}
+		s.totals.ThreadsFailed = len(s.failed)
+	}
+	s.totals.FilesAttempted = s.totals.FilesSaved + s.totals.FilesFailed
+}
+
+// Totals returns the totals so far
+func (s *Summary) Totals() Totals {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.totals
+}
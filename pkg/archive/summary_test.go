@@ -0,0 +1,46 @@
+package archive
+
+import "testing"
+
+func TestSummary(t *testing.T) {
+	s := NewSummary()
+	count := 0
+	obs := Observers{s, nil, ObserverFunc(func(e Event) { count++ })}
+
+	events := []Event{
+		{Type: FileQueued, Bytes: 10},
+		{Type: FileSaved, Bytes: 10},
+		{Type: FileSaved, Bytes: 5},
+		{Type: FileSkipped},
+		{Type: FileFailed},
+		{Type: MD5Mismatch},
+		{Type: ArchiveFailed, Board: "po", No: 3},
+		{Type: ArchiveFailed, Board: "po", No: 3},
+		{Type: ArchiveDone, Board: "po", No: 1},
+		{Type: ArchiveDone, Board: "po", No: 1},
+		{Type: ArchiveDone, Board: "po", No: 2},
+	}
+	for _, e := range events {
+		obs.Observe(e)
+	}
+
+	expected := Totals{
+		Threads:        2,
+		ThreadsFailed:  1,
+		FilesAttempted: 3,
+		FilesSaved:     2,
+		FilesSkipped:   1,
+		FilesFailed:    1,
+		MD5Mismatches:  1,
+		Bytes:          15,
+	}
+	if s.Totals() != expected {
+		t.Errorf("wrong totals, expected: %+v, got: %+v\n", expected, s.Totals())
+	}
+	if !s.Totals().Failed() || (Totals{FilesSaved: 1, MD5Mismatches: 1}).Failed() {
+		t.Errorf("failed runs detected incorrectly\n")
+	}
+	if count != len(events) {
+		t.Errorf("observers received %d events but expected %d\n", count, len(events))
+	}
+}
@@ -0,0 +1,198 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fiwippi/crow/pkg/api"
+)
+
+// FileStatus is the result of verifying a file of a saved thread
+type FileStatus int
+
+const (
+	FileOK      FileStatus = iota // The file exists and its MD5 hash matches, thumbnails can only be checked for existence
+	FileMissing                   // The file doesn't exist
+	FileCorrupt                   // The MD5 hash of the file doesn't match the one supplied by the api
+)
+
+func (s FileStatus) String() string {
+	switch s {
+	case FileOK:
+		return "ok"
+	case FileMissing:
+		return "missing"
+	case FileCorrupt:
+		return "corrupt"
+	default:
+		return "unknown"
+	}
+}
+
+// FileCheck is a file which failed verification
+type FileCheck struct {
+	Kind     string     // The kind of file, "images" or "thumbs"
+	Path     string     // Local path of the file
+	Post     int        // ID of the post the file belongs to
+	Status   FileStatus // Why the file failed verification
+	Repaired bool       // Whether the file was downloaded again
+	Err      error      // The error which occurred while repairing the file
+}
+
+// VerifyResult summarises the verification of a saved thread
+type VerifyResult struct {
+	Board    string
+	No       int
+	Fetched  bool        // Whether the thread was fetched from the api since its thread.json wasn't saved
+	Checked  int         // Number of files checked
+	Problems []FileCheck // Files which are missing or corrupt
+}
+
+// OK returns whether every file is intact or was repaired
+func (r VerifyResult) OK() bool {
+	for _, p := range r.Problems {
+		if !p.Repaired {
+			return false
+		}
+	}
+	return true
+}
+
+// Verify checks that every file of the thread saved in "<Dst>/4chan/<board>/<no>/"
+// exists and that the MD5 hash of every image matches the hash supplied by the api.
+// The posts are read from the saved thread.json, if it wasn't saved then the thread
+// is fetched instead which is only possible while it's still on 4chan. Only files
+// which match the Options' Filter are expected and thumbnails are only expected if
+// the Options don't have FilesOnly set. If repair is true then missing and corrupt
+// files are downloaded again
+func (a *Archiver) Verify(ctx context.Context, board string, no int, repair bool) (VerifyResult, error) {
+	board = strings.Trim(board, "/")
+	c := a.c.WithContext(ctx)
+	res := VerifyResult{Board: board, No: no, Problems: make([]FileCheck, 0)}
+
+	t, err := a.loadThread(board, no)
+	if os.IsNotExist(err) {
+		a.log.Debug().Int("no", no).Str("board", board).Msg("thread.json not saved, fetching thread")
+		var mod bool
+		t, mod, err = c.GetThread(board, no)
+		if err == api.ErrNotFound {
+			return res, fmt.Errorf("thread.json isn't saved and the thread is no longer on 4chan: %w", err)
+		} else if err == nil && !mod {
+			return res, fmt.Errorf("thread.json isn't saved and the thread wasn't returned since it wasn't modified")
+		}
+		res.Fetched = true
+	}
+	if err != nil {
+		return res, err
+	}
+
+	ta := newThreadArchiver(c, a.opts, a.log, board, no)
+	for _, p := range t.Posts {
+		if !a.opts.Filter.Match(p) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+
+		checks := []FileCheck{ta.verifyImage(p)}
+		if !a.opts.FilesOnly {
+			checks = append(checks, ta.verifyThumbnail(p))
+		}
+		for _, fc := range checks {
+			res.Checked++
+			if fc.Status == FileOK {
+				continue
+			}
+
+			a.log.Warn().Str("file", fc.Path).Str("status", fc.Status.String()).Msg("file failed verification")
+			if repair {
+				fc.Err = ta.repair(p, fc.Kind)
+				fc.Repaired = fc.Err == nil
+				if fc.Err != nil {
+					a.log.Error().Err(fc.Err).Str("file", fc.Path).Msg("failed to repair file")
+				}
+			}
+			res.Problems = append(res.Problems, fc)
+		}
+	}
+
+	return res, nil
+}
+
+// loadThread reads the thread from its saved thread.json
+func (a *Archiver) loadThread(board string, no int) (*api.Thread, error) {
+	b, err := os.ReadFile(a.Dir(board, no) + "thread.json")
+	if err != nil {
+		return nil, err
+	}
+	var t api.Thread
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("failed to decode thread.json: %w", err)
+	}
+
+	for _, p := range t.Posts {
+		p.Board = board
+		if p.Filesize > 0 {
+			p.HasFile = true
+		}
+	}
+	return &t, nil
+}
+
+// verifyImage checks the post's image exists and that its MD5 hash matches
+func (a *threadArchiver) verifyImage(p *api.Post) FileCheck {
+	fc := FileCheck{Kind: "images", Path: a.imgDir + p.ImageID.String() + p.Ext, Post: p.No}
+
+	f, err := os.Open(fc.Path)
+	if err != nil {
+		fc.Status = FileMissing
+		return fc
+	}
+	defer f.Close()
+
+	ok, err := api.VerifyFileMD5(p, f)
+	if err != nil || !ok {
+		fc.Status = FileCorrupt
+	}
+	return fc
+}
+
+// verifyThumbnail checks the post's thumbnail exists, thumbnails don't have
+// a hash supplied by the api so they can't be checked for corruption
+func (a *threadArchiver) verifyThumbnail(p *api.Post) FileCheck {
+	fc := FileCheck{Kind: "thumbs", Path: a.thumbDir + p.ImageID.String() + "s.jpg", Post: p.No}
+	if !fileExists(fc.Path) {
+		fc.Status = FileMissing
+	}
+	return fc
+}
+
+// repair downloads the post's image or thumbnail again and saves it
+func (a *threadArchiver) repair(p *api.Post, kind string) error {
+	get, dir := a.c.GetFile, a.imgDir
+	if kind == "thumbs" {
+		get, dir = a.c.GetThumbnail, a.thumbDir
+	}
+
+	m, err := get(p)
+	if err != nil {
+		return err
+	}
+	if kind == "images" && !api.VerifyMD5(p, m) {
+		m.Body.Close()
+		return fmt.Errorf("expected md5 %s but got %s", p.MD5, m.MD5)
+	}
+
+	failed := atomic.LoadInt64(&a.failed)
+	a.queueFile(m, dir, 0, 0, kind)
+	a.wg.Wait()
+	if atomic.LoadInt64(&a.failed) != failed {
+		return fmt.Errorf("failed to save file")
+	}
+	return nil
+}
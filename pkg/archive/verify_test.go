@@ -0,0 +1,64 @@
+package archive
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/fiwippi/crow/pkg/api"
+)
+
+func TestVerify(t *testing.T) {
+	a := New(api.DefaultClient(), Options{Dst: t.TempDir()})
+	ta := a.thread("po", 570368)
+
+	hash := md5.Sum([]byte("image"))
+	post := func(no int) *api.Post {
+		return &api.Post{
+			No:       no,
+			ImageID:  json.Number(strconv.Itoa(no) + "000"),
+			Ext:      ".png",
+			Filesize: 5,
+			MD5:      base64.StdEncoding.EncodeToString(hash[:]),
+		}
+	}
+	thread := &api.Thread{Board: "po", No: 570368, Posts: []*api.Post{post(1), post(2), post(3), {No: 4}}}
+	os.MkdirAll(ta.imgDir, os.ModePerm)
+	os.MkdirAll(ta.thumbDir, os.ModePerm)
+	if err := ta.saveJSON(thread); err != nil {
+		t.Fatalf("failed to save thread: %s\n", err)
+	}
+
+	// Post 1 is intact, post 2's image is corrupt and post 3's files are missing
+	for _, no := range []int{1, 2} {
+		os.WriteFile(ta.thumbDir+strconv.Itoa(no)+"000s.jpg", []byte("thumb"), 0644)
+	}
+	os.WriteFile(ta.imgDir+"1000.png", []byte("image"), 0644)
+	os.WriteFile(ta.imgDir+"2000.png", []byte("corrupt"), 0644)
+
+	res, err := a.Verify(context.Background(), "po", 570368, false)
+	if err != nil {
+		t.Fatalf("failed to verify thread: %s\n", err)
+	}
+	if res.Fetched || res.Checked != 6 || res.OK() {
+		t.Errorf("wrong result: %+v\n", res)
+	}
+
+	expected := []FileCheck{
+		{Kind: "images", Path: ta.imgDir + "2000.png", Post: 2, Status: FileCorrupt},
+		{Kind: "images", Path: ta.imgDir + "3000.png", Post: 3, Status: FileMissing},
+		{Kind: "thumbs", Path: ta.thumbDir + "3000s.jpg", Post: 3, Status: FileMissing},
+	}
+	if len(res.Problems) != len(expected) {
+		t.Fatalf("expected %d problems but got: %+v\n", len(expected), res.Problems)
+	}
+	for i, p := range res.Problems {
+		if p != expected[i] {
+			t.Errorf("wrong problem, expected: %+v, got: %+v\n", expected[i], p)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package archive
+
+import (
+	"context"
+	"time"
+
+	"github.com/fiwippi/crow/pkg/api"
+)
+
+// Threads which haven't been modified for this long are no longer watched
+const staleAfter = 72 * time.Hour
+
+// Watch archives the thread and then checks it for updates every interval,
+// archiving it again whenever it changes. It returns once the thread 404s,
+// is archived or hasn't been modified in 72 hours. If once is true then the
+// thread is archived a single time and Watch returns. If the Options have
+// Boards then the thread is only requested once threads.json shows it changed
+func (a *Archiver) Watch(board string, no int, interval time.Duration, once bool) error {
+	return a.WatchContext(context.Background(), board, no, interval, once)
+}
+
+// WatchContext is the same as Watch except it also returns once the context is
+// done. The thread isn't checked for updates after the context is done and its
+// requests are cancelled, files which have already been downloaded are still
+// saved before it returns. The thread's state is then set to StateStopped
+func (a *Archiver) WatchContext(ctx context.Context, board string, no int, interval time.Duration, once bool) error {
+	c := a.c.WithContext(ctx)
+
+	// Retrieve the thread
+	cache, mod, err := c.GetThread(board, no)
+	if err != nil {
+		if ctx.Err() == nil {
+			a.fail(board, no, err)
+		}
+		return err
+	} else if !mod {
+		// There's no version of the thread to archive if a cache in front of
+		// 4chan says it wasn't modified, this isn't an error since it will
+		// be archived once it's modified again
+		a.log.Warn().Int("no", no).Str("board", board).Msg("thread not modified, skipping")
+		a.setState(board, no, StateStopped)
+		return nil
+	}
+	defer a.forget(cache.Board, cache.No)
+
+	err = a.ArchiveContext(ctx, cache)
+	if err != nil && ctx.Err() == nil {
+		a.log.Error().Err(err).Int("no", cache.No).Str("board", cache.Board).Msg("error archiving thread")
+	}
+	if once || ctx.Err() != nil {
+		a.setState(cache.Board, cache.No, StateStopped)
+		return nil
+	} else if cache.Archived {
+		a.setState(cache.Board, cache.No, StateArchived)
+		return nil
+	}
+	a.setState(cache.Board, cache.No, StateWatching)
+
+	// Use threads.json to find out when the thread changes if possible
+	var wb *watchedBoard
+	var seen time.Time
+	if a.opts.Boards != nil {
+		wb, err = a.opts.Boards.watch(cache.Board)
+		if err != nil {
+			a.log.Warn().Err(err).Str("board", cache.Board).Msg("failed to poll threads.json, polling thread directly")
+		} else {
+			defer a.opts.Boards.release(cache.Board)
+			seen = wb.lastModified(cache.No)
+		}
+	}
+
+	// Ticker to check the thread at intervals
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// lastCall keeps track of when the thread was last modified
+	lastCall := time.Now()
+
+	// Check the thread at intervals until it 404s, is archived or the context is done
+	for {
+		select {
+		case <-ctx.Done():
+			a.log.Info().Int("no", cache.No).Str("board", cache.Board).Msg("stopped watching thread")
+			a.setState(cache.Board, cache.No, StateStopped)
+			return nil
+		case <-ticker.C:
+		}
+
+		// Only request the thread if threads.json says it changed, threads
+		// missing from threads.json are requested to find out if they 404'd
+		// or were archived
+		if wb != nil && !wb.changed(cache.No, &seen) {
+			if a.stale(cache, lastCall) {
+				return nil
+			}
+			continue
+		}
+
+		// Get the newest version of the thread
+		t, mod, err := c.RefreshThread(cache)
+		if err == api.ErrNotFound {
+			a.log.Info().Int("no", cache.No).Str("board", cache.Board).Msg("thread 404'd")
+			a.setState(cache.Board, cache.No, StateNotFound)
+			return nil
+		} else if err != nil {
+			if ctx.Err() == nil {
+				a.log.Error().Err(err).Int("no", cache.No).Str("board", cache.Board).Msg("error refreshing thread")
+			}
+			continue
+		} else if !mod {
+			if a.stale(cache, lastCall) {
+				return nil
+			}
+			continue
+		}
+
+		// Thread has changed so update lastCall
+		lastCall = time.Now()
+		d := api.DiffThread(cache, t)
+		a.log.Debug().Int("no", t.No).Str("board", t.Board).Int("new_posts", len(d.NewPosts)).
+			Int("deleted_posts", len(d.DeletedPosts)).Int("deleted_files", len(d.DeletedFiles)).Msg("thread updated")
+		cache = t
+
+		// Archive the thread
+		err = a.ArchiveContext(ctx, t)
+		if err != nil && ctx.Err() == nil {
+			a.log.Error().Err(err).Int("no", t.No).Str("board", t.Board).Msg("error archiving thread")
+		}
+		if t.Archived {
+			a.log.Info().Int("no", t.No).Str("board", t.Board).Msg("thread archived")
+			a.setState(t.Board, t.No, StateArchived)
+			return nil
+		}
+	}
+}
+
+// stale returns whether the thread hasn't been modified for long enough
+// that it should no longer be watched, if so the state is set to stale
+func (a *Archiver) stale(t *api.Thread, lastCall time.Time) bool {
+	if time.Since(lastCall) <= staleAfter {
+		return false
+	}
+	a.log.Info().Int("no", t.No).Str("board", t.Board).Msg("thread has not been modified in 72 hours")
+	a.setState(t.Board, t.No, StateStale)
+	return true
+}
+
+// fail notifies the observer that the thread couldn't be archived
+func (a *Archiver) fail(board string, no int, err error) {
+	if a.opts.Observer != nil {
+		a.opts.Observer.Observe(Event{Type: ArchiveFailed, Board: board, No: no, Err: err})
+	}
+}
+
+// setState notifies the observer that the thread's state has changed
+func (a *Archiver) setState(board string, no int, s State) {
+	if a.opts.Observer != nil {
+		a.opts.Observer.Observe(Event{Type: StateChanged, Board: board, No: no, State: s})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/fiwippi/crow/internal/log"
+	"github.com/fiwippi/crow/pkg/archive"
+)
+
+// runReport is what was archived while watching threads, it's
+// written as JSON so scheduled runs can check whether they failed
+type runReport struct {
+	archive.Totals
+	Started  time.Time `json:"started"`
+	Duration float64   `json:"duration_seconds"`
+	TimedOut bool      `json:"timed_out"` // Whether threads didn't finish saving their files within shutdownTimeout
+	OK       bool      `json:"ok"`        // Whether every thread and file was archived
+
+	elapsed time.Duration
+}
+
+func newRunReport(t archive.Totals, start time.Time, timedOut bool) runReport {
+	elapsed := time.Since(start)
+	return runReport{
+		Totals:   t,
+		Started:  start,
+		Duration: elapsed.Round(time.Millisecond).Seconds(),
+		TimedOut: timedOut,
+		OK:       !t.Failed() && !timedOut,
+		elapsed:  elapsed,
+	}
+}
+
+// log logs the report as a summary of the run
+func (r runReport) log() {
+	e := log.Info()
+	if !r.OK {
+		e = log.Error()
+	}
+	e.Int("threads", r.Threads).Int("threads_failed", r.ThreadsFailed).
+		Int("files_attempted", r.FilesAttempted).Int("files_saved", r.FilesSaved).
+		Int("files_skipped", r.FilesSkipped).Int("files_failed", r.FilesFailed).
+		Int("md5_mismatches", r.MD5Mismatches).Int64("bytes", r.Bytes).
+		Str("time_taken", r.elapsed.Round(time.Second).String()).
+		Bool("ok", r.OK).Msg("finished watching threads")
+}
+
+// write writes the report as JSON to the path, "-" writes it to stdout
+func (r runReport) write(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// finish logs the report, writes it to the path if one is given and
+// exits with status 1 if anything failed, this should be the last
+// thing a command does
+func (r runReport) finish(path string) {
+	r.log()
+	if path != "" {
+		if err := r.write(path); err != nil {
+			log.Error().Err(err).Str("path", path).Msg("failed to write report")
+			os.Exit(1)
+		}
+	}
+	if !r.OK {
+		os.Exit(1)
+	}
+}
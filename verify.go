@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fiwippi/crow/internal/log"
+	"github.com/fiwippi/crow/pkg/api"
+	"github.com/fiwippi/crow/pkg/archive"
+)
+
+// verify checks the saved files of a thread or of every saved thread on a board
+func verify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	sf := newSettingsFlags(fs)
+	repair := fs.Bool("repair", false, "Whether to download missing and corrupt files again")
+	fs.Usage = usage(fs, "Check every image and thumbnail of saved threads exists and that the MD5 hash of each image "+
+		"is correct, crow exits with status 1 if any file is missing or corrupt and wasn't repaired",
+		"verify po 570368",
+		"verify -repair https://boards.4channel.org/po/thread/570368",
+		"verify -dst archive po",
+	)
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	cfg := sf.load()
+
+	// A single board verifies every thread saved for the board
+	var board string
+	var threads []int
+	if l, err := api.ParseLink(fs.Arg(0)); fs.NArg() == 1 && err == nil && l.Board != "" && l.Thread == 0 && l.Post == 0 {
+		board = l.Board
+		threads, err = savedThreads(*sf.settings(cfg, board, 0, 0).Dst, board)
+		if err != nil {
+			log.Fatal().Err(err).Str("board", board).Msg("failed to find saved threads")
+		}
+	} else {
+		var no int
+		board, no, err = parseThread(fs.Args())
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to parse 4chan url")
+		}
+		threads = []int{no}
+	}
+
+	c := newClient(cfg, nil)
+	ctx := signalContext()
+	ok := true
+	for _, no := range threads {
+		if ctx.Err() != nil {
+			os.Exit(1)
+		}
+		a := archive.New(c, options(sf.settings(cfg, board, no, 0)))
+		res, err := a.Verify(ctx, board, no, *repair)
+		if err != nil {
+			log.Error().Err(err).Int("no", no).Str("board", board).Msg("failed to verify thread")
+			ok = false
+			continue
+		}
+
+		for _, p := range res.Problems {
+			status := p.Status.String()
+			if p.Repaired {
+				status += ", repaired"
+			} else if p.Err != nil {
+				status += ", " + p.Err.Error()
+			}
+			fmt.Printf("/%s/%d: %s (%s)\n", board, no, p.Path, status)
+		}
+		fmt.Printf("/%s/%d: checked %d files, %d problems\n", board, no, res.Checked, len(res.Problems))
+		ok = ok && res.OK()
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// savedThreads returns the threads saved for the board in ascending order
+func savedThreads(dst, board string) ([]int, error) {
+	entries, err := os.ReadDir(fmt.Sprintf("%s/4chan/%s", strings.TrimSuffix(dst, "/"), board))
+	if err != nil {
+		return nil, err
+	}
+
+	threads := make([]int, 0)
+	for _, e := range entries {
+		if no, err := strconv.Atoi(e.Name()); err == nil && e.IsDir() {
+			threads = append(threads, no)
+		}
+	}
+	sort.Ints(threads)
+	return threads, nil
+}
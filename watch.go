@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fiwippi/crow/internal/config"
+	"github.com/fiwippi/crow/internal/log"
+	"github.com/fiwippi/crow/internal/metrics"
+	"github.com/fiwippi/crow/pkg/archive"
+)
+
+// How long threads are given to finish saving their files once crow is stopped
+const shutdownTimeout = 30 * time.Second
+
+// watch archives the thread given as arguments and the threads in the config
+// file, then keeps archiving them as they update until they 404 or are archived
+func watch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	sf := newSettingsFlags(fs)
+	runOnce := fs.Bool("run-once", false, "Download the thread once and exit without checking for updates, the same as get")
+	interval := fs.Duration("interval", 5*time.Minute, "How often to check if the thread updated")
+	metricsAddr := fs.String("metrics-addr", "", "Address to serve Prometheus metrics on at /metrics, e.g. :9090, disabled if empty")
+	report := fs.String("report", "", "Path to write a JSON report of what was archived to once every thread stops being watched, - for stdout")
+	fs.Usage = usage(fs, "Archive threads and keep archiving them as they update until they 404 or are archived, "+
+		"crow exits with status 1 if any thread or file failed to archive",
+		"watch po 570368",
+		"watch po/thread/570368",
+		"watch https://boards.4channel.org/po/thread/570368",
+		"watch '>>>/po/570368'",
+		"watch -config crow.toml",
+		"po 570368",
+	)
+	fs.Parse(args)
+	cfg := sf.load()
+
+	threads := threadArgs(fs, cfg)
+	m := metrics.New()
+	if addr := sf.override("metrics-addr", *metricsAddr, cfg.MetricsAddr); addr != "" {
+		go func() {
+			err := serveMetrics(addr, m)
+			if err != nil {
+				log.Error().Err(err).Str("addr", addr).Msg("failed to serve metrics")
+			}
+		}()
+	}
+
+	watchThreads(signalContext(), sf, cfg, m, threads, *interval, *runOnce).finish(*report)
+}
+
+// get archives the thread given as arguments and the threads in the config file once
+func get(args []string) {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	sf := newSettingsFlags(fs)
+	report := fs.String("report", "", "Path to write a JSON report of what was archived to, - for stdout")
+	fs.Usage = usage(fs, "Archive threads once without checking for updates, "+
+		"crow exits with status 1 if any thread or file failed to archive",
+		"get po 570368",
+		"get https://boards.4channel.org/po/thread/570368",
+		"get -format html,json -dst archive '>>>/po/570368'",
+		"get -config crow.toml -report report.json",
+	)
+	fs.Parse(args)
+	cfg := sf.load()
+
+	watchThreads(signalContext(), sf, cfg, metrics.New(), threadArgs(fs, cfg), 0, true).finish(*report)
+}
+
+// serve watches the threads in the config file and serves metrics, unlike watch
+// it keeps serving metrics once every thread has stopped being watched
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	sf := newSettingsFlags(fs)
+	interval := fs.Duration("interval", 5*time.Minute, "How often to check if the threads updated")
+	metricsAddr := fs.String("metrics-addr", ":9090", "Address to serve Prometheus metrics on at /metrics")
+	fs.Usage = usage(fs, "Watch the threads in the config file and serve metrics until stopped",
+		"serve -config crow.toml",
+		"serve -config crow.toml -metrics-addr localhost:9090",
+	)
+	fs.Parse(args)
+	if *sf.configPath == "" || fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	cfg := sf.load()
+	if len(cfg.Watch) == 0 {
+		log.Fatal().Str("path", *sf.configPath).Msg("config has no threads to watch")
+	}
+
+	m := metrics.New()
+	addr := sf.override("metrics-addr", *metricsAddr, cfg.MetricsAddr)
+	go func() {
+		err := serveMetrics(addr, m)
+		if err != nil {
+			log.Fatal().Err(err).Str("addr", addr).Msg("failed to serve metrics")
+		}
+	}()
+
+	// Metrics are served until crow is stopped even if every thread has stopped being watched
+	ctx := signalContext()
+	watchThreads(ctx, sf, cfg, m, cfg.Watch, *interval, false).log()
+	<-ctx.Done()
+}
+
+// threadArgs returns the thread given as arguments followed by the threads in
+// the config file, if there are none then the usage is printed and crow exits
+func threadArgs(fs *flag.FlagSet, cfg *config.Config) []config.Watch {
+	threads := make([]config.Watch, 0)
+	if fs.NArg() > 0 {
+		board, thread, err := parseThread(fs.Args())
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to parse 4chan url")
+		}
+		threads = append(threads, config.Watch{Board: board, Thread: thread})
+	}
+	threads = append(threads, cfg.Watch...)
+	if len(threads) == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	return threads
+}
+
+// watchThreads watches the threads until every one of them stops being watched or
+// the context is done, then the threads are given shutdownTimeout to finish saving
+// the files they've downloaded and a report of what was archived is returned
+func watchThreads(ctx context.Context, sf *settingsFlags, cfg *config.Config, m *metrics.Metrics, threads []config.Watch, interval time.Duration, once bool) runReport {
+	// Create the client
+	c := newClient(cfg, m)
+
+	// Threads on the same board share a poller of the board's threads.json
+	// which is polled as often as the most frequently checked thread
+	settings := make([]config.Settings, len(threads))
+	minInterval := time.Duration(0)
+	for i, t := range threads {
+		settings[i] = sf.settings(cfg, t.Board, t.Thread, interval)
+		if d := settings[i].Interval.Duration; minInterval == 0 || d < minInterval {
+			minInterval = d
+		}
+	}
+	boards := archive.NewBoards(c, minInterval, log.Logger())
+	summary := archive.NewSummary()
+	start := time.Now()
+
+	// Watch the threads, the client is shared so
+	// all threads are subject to the same rate limit
+	wg := &sync.WaitGroup{}
+	for i, t := range threads {
+		s := settings[i]
+
+		opts := options(s)
+		opts.Observer = archive.Observers{m, summary}
+		opts.Boards = boards
+		a := archive.New(c, opts)
+
+		wg.Add(1)
+		go func(board string, thread int, interval time.Duration) {
+			defer wg.Done()
+
+			err := a.WatchContext(ctx, board, thread, interval, once)
+			if err != nil && ctx.Err() == nil {
+				log.Error().Err(err).Int("no", thread).Str("board", board).Msg("failed to get thread")
+			}
+		}(t.Board, t.Thread, s.Interval.Duration)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timedOut := false
+	select {
+	case <-done:
+	case <-ctx.Done():
+		select {
+		case <-done:
+		case <-time.After(shutdownTimeout):
+			log.Error().Str("timeout", shutdownTimeout.String()).Msg("timed out waiting for threads to finish saving")
+			timedOut = true
+		}
+	}
+
+	return newRunReport(summary.Totals(), start, timedOut)
+}